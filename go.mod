@@ -13,4 +13,5 @@ require (
 	github.com/sirupsen/logrus v1.6.0
 	github.com/stretchr/testify v1.2.2
 	golang.org/x/net v0.0.0-20200707034311-ab3426394381 // indirect
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,93 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"io"
+)
+
+// WithStdout sets the writer that receives the container's stdout, when run
+// attached or while it runs in the background. It defaults to the writer set
+// with WithOutput, which discards it.
+func (r *ContainerRunner) WithStdout(w io.Writer) *ContainerRunner {
+	r.stdout = w
+	return r
+}
+
+// WithStderr sets the writer that receives the container's stderr, see
+// WithStdout
+func (r *ContainerRunner) WithStderr(w io.Writer) *ContainerRunner {
+	r.stderr = w
+	return r
+}
+
+// streams returns the writers of the container's stdout and stderr
+func (e *ContainerRunner) streams() (io.Writer, io.Writer) {
+	stdout, stderr := e.stdout, e.stderr
+	if stdout == nil {
+		stdout = e.output
+	}
+	if stderr == nil {
+		stderr = e.output
+	}
+	return stdout, stderr
+}
+
+// streamLogs follows the logs of the started container in the background and
+// copies them to the stdout and stderr writers until the container stops
+func (e *ContainerRunner) streamLogs() error {
+	// The logs outlive the context of Start and end with the container
+	logs, err := e.client.ContainerLogs(context.Background(), e.id, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+	})
+	if err != nil {
+		return fmt.Errorf("following logs: %w", err)
+	}
+	stdout, stderr := e.streams()
+	go func() {
+		defer logs.Close()
+		if _, err := stdcopy.StdCopy(stdout, stderr, logs); err != nil {
+			e.logger.Warnf("copying container output: %v", err)
+		}
+	}()
+	return nil
+}
+
+// startAttached attaches to the created container, starts it and copies its
+// output until it exits
+func (e *ContainerRunner) startAttached(ctx context.Context) error {
+	e.logger.Infoln("attaching to container")
+	stream, err := e.client.ContainerAttach(ctx, e.id, types.ContainerAttachOptions{
+		Stream: true,
+		Stdout: true,
+		Stderr: true,
+	})
+	if err != nil {
+		return fmt.Errorf("attaching to container: %w", err)
+	}
+	defer stream.Close()
+
+	e.logger.Infoln("starting container")
+	if err := e.client.ContainerStart(ctx, e.id, types.ContainerStartOptions{}); err != nil {
+		return fmt.Errorf("starting container: %w", classifyError(err))
+	}
+	e.logger.Infoln("container started")
+
+	stdout, stderr := e.streams()
+	if _, err := stdcopy.StdCopy(stdout, stderr, stream.Reader); err != nil {
+		return fmt.Errorf("copying container output: %w", err)
+	}
+	code, err := e.WaitForExit(ctx)
+	if err != nil {
+		return err
+	}
+	e.logger.Infof("container exited with code %v", code)
+	if code != 0 {
+		return &ExitError{Code: code}
+	}
+	return nil
+}
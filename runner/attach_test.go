@@ -0,0 +1,93 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestWithStdout(t *testing.T) {
+	var logs bytes.Buffer
+	stdcopy.NewStdWriter(&logs, stdcopy.Stdout).Write([]byte("ready to accept connections\n"))
+	stdcopy.NewStdWriter(&logs, stdcopy.Stderr).Write([]byte("deprecated option\n"))
+
+	var stdout, stderr safeBuffer
+	runner := NewContainerRunner().
+		WithImage("redis").
+		WithStdout(&stdout).
+		WithStderr(&stderr)
+	runner.client = &mockClient{
+		containerLogs: func(id string, options types.ContainerLogsOptions) (io.ReadCloser, error) {
+			return ioutil.NopCloser(&logs), nil
+		},
+	}
+	require.NoError(t, runner.Start(context.Background()))
+
+	// The logs are copied in the background
+	deadline := time.Now().Add(time.Second)
+	for len(stderr.String()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Equal(t, "ready to accept connections\n", stdout.String())
+	require.Equal(t, "deprecated option\n", stderr.String())
+}
+
+func TestWithAttach(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		code int64
+	}{
+		{"success", 0},
+		{"failure", 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server, conn := net.Pipe()
+			started := false
+			var stdout, stderr bytes.Buffer
+			runner := NewContainerRunner().
+				WithImage("busybox").
+				WithAttach(true).
+				WithStdout(&stdout).
+				WithStderr(&stderr)
+			runner.client = &mockClient{
+				containerAttach: func(options types.ContainerAttachOptions) (types.HijackedResponse, error) {
+					require.True(t, options.Stream)
+					require.False(t, started, "attached after the container started")
+					return types.HijackedResponse{Conn: conn, Reader: bufio.NewReader(conn)}, nil
+				},
+				containerStart: func(id string) error {
+					started = true
+					go func() {
+						stdcopy.NewStdWriter(server, stdcopy.Stdout).Write([]byte("migrating\n"))
+						stdcopy.NewStdWriter(server, stdcopy.Stderr).Write([]byte("migration failed\n"))
+						server.Close()
+					}()
+					return nil
+				},
+				containerWait: func(id string) (int64, error) {
+					return tc.code, nil
+				},
+			}
+
+			err := runner.Start(context.Background())
+			require.Equal(t, "migrating\n", stdout.String())
+			require.Equal(t, "migration failed\n", stderr.String())
+			if tc.code == 0 {
+				require.NoError(t, err)
+				return
+			}
+			var exitErr *ExitError
+			require.True(t, errors.As(err, &exitErr))
+			require.Equal(t, tc.code, exitErr.Code)
+		})
+	}
+}
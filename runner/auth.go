@@ -0,0 +1,132 @@
+package runner
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"io/ioutil"
+	"strings"
+)
+
+// dockerHubRegistry is the host that image references without a registry
+// resolve to
+const dockerHubRegistry = "docker.io"
+
+// dockerConfigJSON is the format of ~/.docker/config.json and of Kubernetes
+// secrets of type kubernetes.io/dockerconfigjson
+type dockerConfigJSON struct {
+	Auths map[string]dockerConfigAuth `json:"auths"`
+}
+
+type dockerConfigAuth struct {
+	Auth     string `json:"auth"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// WithRegistryAuth sets the credentials used to pull the image
+func (r *ContainerRunner) WithRegistryAuth(auth types.AuthConfig) *ContainerRunner {
+	r.registryAuths = map[string]types.AuthConfig{"": auth}
+	return r
+}
+
+// WithRegistryAuthFromDockerConfigJSON reads registry credentials from a file
+// in the .dockerconfigjson format, such as a mounted Kubernetes pull secret or
+// ~/.docker/config.json. The entry matching the registry host of the image
+// is used when pulling. If there is no such entry, the image is pulled
+// without credentials.
+func (r *ContainerRunner) WithRegistryAuthFromDockerConfigJSON(path string) *ContainerRunner {
+	auths, err := readDockerConfigJSON(path)
+	if err != nil {
+		r.setErr(err)
+		return r
+	}
+	r.registryAuths = auths
+	return r
+}
+
+// readDockerConfigJSON parses the credentials of a .dockerconfigjson file,
+// keyed by normalized registry host
+func readDockerConfigJSON(path string) (map[string]types.AuthConfig, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading docker config: %w", err)
+	}
+	var config dockerConfigJSON
+	if err := json.Unmarshal(b, &config); err != nil {
+		return nil, fmt.Errorf("parsing docker config %v: %w", path, err)
+	}
+
+	auths := map[string]types.AuthConfig{}
+	for registry, entry := range config.Auths {
+		auth := types.AuthConfig{
+			Username:      entry.Username,
+			Password:      entry.Password,
+			ServerAddress: registry,
+		}
+		if len(entry.Auth) > 0 {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("decoding auth of registry %v: %w", registry, err)
+			}
+			parts := strings.SplitN(string(decoded), ":", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("auth of registry %v is not user:password", registry)
+			}
+			auth.Username, auth.Password = parts[0], parts[1]
+		}
+		auths[normalizeRegistry(registry)] = auth
+	}
+	return auths, nil
+}
+
+// registryAuth returns the encoded credentials for pulling the image, or an
+// empty string if there are none
+func (e *ContainerRunner) registryAuth() (string, error) {
+	if len(e.registryAuths) == 0 {
+		return "", nil
+	}
+	auth, ok := e.registryAuths[""]
+	if !ok {
+		host := registryHost(e.image)
+		auth, ok = e.registryAuths[host]
+		if !ok {
+			e.logger.Warnf("no credentials for registry %v, pulling without authentication", host)
+			return "", nil
+		}
+	}
+	b, err := json.Marshal(auth)
+	if err != nil {
+		return "", fmt.Errorf("encoding registry auth: %w", err)
+	}
+	return base64.URLEncoding.EncodeToString(b), nil
+}
+
+// registryHost returns the registry host of an image reference
+func registryHost(image string) string {
+	i := strings.Index(image, "/")
+	if i == -1 {
+		return dockerHubRegistry
+	}
+	host := image[:i]
+	if !strings.ContainsAny(host, ".:") && host != "localhost" {
+		return dockerHubRegistry
+	}
+	return normalizeRegistry(host)
+}
+
+// normalizeRegistry strips the scheme and path of a registry key, and maps
+// the aliases of Docker Hub to a single host
+func normalizeRegistry(registry string) string {
+	registry = strings.TrimPrefix(registry, "https://")
+	registry = strings.TrimPrefix(registry, "http://")
+	if i := strings.Index(registry, "/"); i != -1 {
+		registry = registry[:i]
+	}
+	switch registry {
+	case "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return dockerHubRegistry
+	}
+	return registry
+}
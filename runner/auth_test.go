@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithRegistryAuthFromDockerConfigJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	require.NoError(t, err)
+	path := filepath.Join(dir, ".dockerconfigjson")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"auths": {
+		"https://index.docker.io/v1/": {"auth": "`+base64.StdEncoding.EncodeToString([]byte("hub:secret"))+`"},
+		"registry.example.com:5000": {"auth": "`+base64.StdEncoding.EncodeToString([]byte("ci:p:ss"))+`"}
+	}}`), 0600))
+
+	var testCases = []struct {
+		name     string
+		image    string
+		username string
+		password string
+	}{
+		{name: "docker hub", image: "mongo", username: "hub", password: "secret"},
+		{name: "private registry", image: "registry.example.com:5000/team/app", username: "ci", password: "p:ss"},
+		{name: "no matching registry", image: "quay.io/team/app"},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			runner := NewContainerRunner().
+				WithImage(c.image).
+				WithRegistryAuthFromDockerConfigJSON(path)
+			require.NoError(t, runner.err)
+
+			encoded, err := runner.registryAuth()
+			require.NoError(t, err)
+			if len(c.username) == 0 {
+				require.Empty(t, encoded)
+				return
+			}
+			b, err := base64.URLEncoding.DecodeString(encoded)
+			require.NoError(t, err)
+			var auth types.AuthConfig
+			require.NoError(t, json.Unmarshal(b, &auth))
+			require.Equal(t, c.username, auth.Username)
+			require.Equal(t, c.password, auth.Password)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package runner
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/google/uuid"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// buildSpec describes how the image is built by WithBuild
+type buildSpec struct {
+	contextDir string
+	dockerfile string
+}
+
+// WithBuild builds the image from a Dockerfile in Start instead of pulling
+// it. The whole of contextDir is sent to the daemon as the build context,
+// .dockerignore files are not taken into account. dockerfile is relative to
+// contextDir and defaults to "Dockerfile". The image is tagged with the
+// reference set with WithImage, or with a generated one.
+func (r *ContainerRunner) WithBuild(contextDir, dockerfile string) *ContainerRunner {
+	if len(dockerfile) == 0 {
+		dockerfile = "Dockerfile"
+	}
+	r.build = &buildSpec{contextDir: contextDir, dockerfile: dockerfile}
+	return r
+}
+
+// WithBuildArg sets a build-time variable declared with ARG in the Dockerfile.
+// It requires WithBuild.
+func (r *ContainerRunner) WithBuildArg(key, val string) *ContainerRunner {
+	if r.buildArgs == nil {
+		r.buildArgs = map[string]*string{}
+	}
+	r.buildArgs[key] = &val
+	return r
+}
+
+// buildImage builds the image and sets the runner's image to its tag
+func (e *ContainerRunner) buildImage(ctx context.Context) error {
+	if len(e.image) == 0 {
+		e.image = "container-build:" + strings.SplitN(uuid.New().String(), "-", 2)[0]
+	}
+	buildContext, err := archiveDir(e.build.contextDir)
+	if err != nil {
+		return fmt.Errorf("archiving build context: %w", err)
+	}
+
+	e.logger.Infoln("building image")
+	resp, err := e.client.ImageBuild(ctx, buildContext, types.ImageBuildOptions{
+		Tags:       []string{e.image},
+		Dockerfile: e.build.dockerfile,
+		BuildArgs:  e.buildArgs,
+		Remove:     true,
+	})
+	if err != nil {
+		return fmt.Errorf("building image: %w", err)
+	}
+	if err := e.displayProgress(ctx, resp.Body); err != nil {
+		return fmt.Errorf("building image: %w", err)
+	}
+	e.logger.Infof("built image %v", e.image)
+	return nil
+}
+
+// archiveDir returns a tar archive of the files in dir
+func archiveDir(dir string) (io.Reader, error) {
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err = os.Readlink(path)
+			if err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(name)
+		if err := w.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"archive/tar"
+	"context"
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWithBuildArg(t *testing.T) {
+	dir, err := ioutil.TempDir("", "build")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("ARG VERSION\nFROM alpine:${VERSION}\n"), 0644))
+
+	var files []string
+	var options types.ImageBuildOptions
+	runner := NewContainerRunner().
+		WithRawImage("app:test").
+		WithBuild(dir, "").
+		WithBuildArg("VERSION", "3.12")
+	runner.client = &mockClient{
+		imageBuild: func(buildContext io.Reader, opts types.ImageBuildOptions) (types.ImageBuildResponse, error) {
+			options = opts
+			r := tar.NewReader(buildContext)
+			for {
+				header, err := r.Next()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				files = append(files, header.Name)
+			}
+			return types.ImageBuildResponse{Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+		},
+	}
+	require.NoError(t, runner.Start(context.Background()))
+
+	require.Equal(t, []string{"Dockerfile"}, files)
+	require.Equal(t, []string{"app:test"}, options.Tags)
+	require.Equal(t, "Dockerfile", options.Dockerfile)
+	require.Equal(t, "3.12", *options.BuildArgs["VERSION"])
+
+	runner = NewContainerRunner().WithImage("alpine").WithBuildArg("VERSION", "3.12")
+	runner.client = &mockClient{}
+	require.Error(t, runner.Start(context.Background()))
+}
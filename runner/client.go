@@ -0,0 +1,90 @@
+package runner
+
+import (
+	"fmt"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/sockets"
+	"github.com/docker/go-connections/tlsconfig"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// WithClientTimeout bounds every request the runner makes to the daemon by
+// timeout, as a backstop against a daemon that stopped responding. The bound
+// includes reading the response, so it must be longer than the longest
+// operation, such as pulling the image, following logs during a wait or
+// waiting for the container to exit. There is no timeout by default. It has
+// no effect if the runner already has a client.
+func (r *ContainerRunner) WithClientTimeout(timeout time.Duration) *ContainerRunner {
+	if timeout < 0 {
+		r.setErr(fmt.Errorf("client timeout %v must not be negative", timeout))
+		return r
+	}
+	r.clientTimeout = timeout
+	return r
+}
+
+// newEnvClient creates a docker client from the environment like
+// client.NewEnvClient, whose HTTP client has the given timeout. NewEnvClient
+// cannot be given an HTTP client, and NewClient only accepts an
+// *http.Transport, so its setup is repeated here rather than wrapped. Like
+// NewEnvClient, a version pinned with DOCKER_API_VERSION is kept by
+// UpdateClientVersion.
+func newEnvClient(timeout time.Duration) (client.CommonAPIClient, error) {
+	if timeout == 0 {
+		c, err := client.NewEnvClient()
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+
+	host := os.Getenv("DOCKER_HOST")
+	if len(host) == 0 {
+		host = client.DefaultDockerHost
+	}
+	proto, addr, _, err := client.ParseHost(host)
+	if err != nil {
+		return nil, err
+	}
+	transport := new(http.Transport)
+	if err := sockets.ConfigureTransport(transport, proto, addr); err != nil {
+		return nil, err
+	}
+	if certPath := os.Getenv("DOCKER_CERT_PATH"); len(certPath) > 0 {
+		tlsc, err := tlsconfig.Client(tlsconfig.Options{
+			CAFile:             filepath.Join(certPath, "ca.pem"),
+			CertFile:           filepath.Join(certPath, "cert.pem"),
+			KeyFile:            filepath.Join(certPath, "key.pem"),
+			InsecureSkipVerify: len(os.Getenv("DOCKER_TLS_VERIFY")) == 0,
+		})
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsc
+	}
+
+	version := os.Getenv("DOCKER_API_VERSION")
+	if len(version) == 0 {
+		version = client.DefaultVersion
+	}
+	c, err := client.NewClient(host, version, &http.Client{Transport: transport, Timeout: timeout}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(os.Getenv("DOCKER_API_VERSION")) > 0 {
+		return pinnedVersionClient{c}, nil
+	}
+	return c, nil
+}
+
+// pinnedVersionClient is a client whose API version was set by the user,
+// which NewEnvClient marks with an unexported field
+type pinnedVersionClient struct {
+	*client.Client
+}
+
+// UpdateClientVersion keeps the pinned version
+func (pinnedVersionClient) UpdateClientVersion(v string) {}
@@ -0,0 +1,65 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithClientTimeout(t *testing.T) {
+	// A daemon that stopped responding
+	unblock := make(chan struct{})
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer daemon.Close()
+	defer close(unblock)
+
+	host := os.Getenv("DOCKER_HOST")
+	defer os.Setenv("DOCKER_HOST", host)
+	os.Setenv("DOCKER_HOST", "tcp://"+strings.TrimPrefix(daemon.URL, "http://"))
+
+	runner := NewContainerRunner().WithClientTimeout(50 * time.Millisecond)
+	started := time.Now()
+	err := runner.Ping(context.Background())
+	require.True(t, errors.Is(err, ErrDaemonUnreachable))
+	require.True(t, time.Since(started) < 5*time.Second)
+}
+
+func TestNewEnvClientVersion(t *testing.T) {
+	if version, ok := os.LookupEnv("DOCKER_API_VERSION"); ok {
+		defer os.Setenv("DOCKER_API_VERSION", version)
+	} else {
+		defer os.Unsetenv("DOCKER_API_VERSION")
+	}
+	for _, tc := range []struct {
+		name    string
+		timeout time.Duration
+		pinned  bool
+	}{
+		{"default", 0, false},
+		{"pinned", 0, true},
+		{"timeout", time.Minute, false},
+		{"pinned with timeout", time.Minute, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			want := "1.30"
+			if tc.pinned {
+				want = "1.24"
+				require.NoError(t, os.Setenv("DOCKER_API_VERSION", want))
+			} else {
+				require.NoError(t, os.Unsetenv("DOCKER_API_VERSION"))
+			}
+			c, err := newEnvClient(tc.timeout)
+			require.NoError(t, err)
+			c.UpdateClientVersion("1.30")
+			require.Equal(t, want, c.ClientVersion())
+		})
+	}
+}
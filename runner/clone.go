@@ -0,0 +1,156 @@
+package runner
+
+import (
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/go-connections/nat"
+	log "github.com/sirupsen/logrus"
+	"sync/atomic"
+)
+
+// Clone returns a copy of the runner's configuration that can be modified and
+// started independently of the original. Slices and maps are deep copied so
+// that builder calls on either runner never affect the other, and the clone
+// does not share the original's container or client.
+func (r *ContainerRunner) Clone() *ContainerRunner {
+	c := *r
+	c.ports = copyStrings(r.ports)
+	c.env = copyStrings(r.env)
+	c.fileEnv = copyStrings(r.fileEnv)
+	c.explicitEnv = copyStrings(r.explicitEnv)
+	c.envTemplates = append([]envTemplate(nil), r.envTemplates...)
+	c.retryable = append([]func(error) bool(nil), r.retryable...)
+	if r.secretEnv != nil {
+		c.secretEnv = make(map[string]struct{}, len(r.secretEnv))
+		for key := range r.secretEnv {
+			c.secretEnv[key] = struct{}{}
+		}
+	}
+	c.binds = copyStrings(r.binds)
+	c.dnsOptions = copyStrings(r.dnsOptions)
+	c.extraHosts = copyStrings(r.extraHosts)
+	c.cmd = copyStrings(r.cmd)
+	c.entrypoint = copyStrings(r.entrypoint)
+	c.mounts = append([]mount.Mount(nil), r.mounts...)
+	for i, m := range c.mounts {
+		if m.BindOptions != nil {
+			options := *m.BindOptions
+			c.mounts[i].BindOptions = &options
+		}
+		if m.VolumeOptions != nil {
+			options := *m.VolumeOptions
+			options.Labels = copyStringMap(m.VolumeOptions.Labels)
+			if options.DriverConfig != nil {
+				driver := *options.DriverConfig
+				driver.Options = copyStringMap(driver.Options)
+				options.DriverConfig = &driver
+			}
+			c.mounts[i].VolumeOptions = &options
+		}
+		if m.TmpfsOptions != nil {
+			options := *m.TmpfsOptions
+			c.mounts[i].TmpfsOptions = &options
+		}
+	}
+	c.sysctls = copyStringMap(r.sysctls)
+	c.metadata = copyStringMap(r.metadata)
+	c.labels = copyStringMap(r.labels)
+	if r.build != nil {
+		build := *r.build
+		c.build = &build
+	}
+	if r.buildArgs != nil {
+		c.buildArgs = make(map[string]*string, len(r.buildArgs))
+		for key, val := range r.buildArgs {
+			c.buildArgs[key] = val
+		}
+	}
+	c.logConfig.Config = copyStringMap(r.logConfig.Config)
+	if r.registryAuths != nil {
+		c.registryAuths = make(map[string]types.AuthConfig, len(r.registryAuths))
+		for host, auth := range r.registryAuths {
+			c.registryAuths[host] = auth
+		}
+	}
+	if r.logFields != nil {
+		c.logFields = log.Fields{}
+		for k, v := range r.logFields {
+			c.logFields[k] = v
+		}
+	}
+	if r.healthcheck != nil {
+		healthcheck := *r.healthcheck
+		healthcheck.Test = copyStrings(r.healthcheck.Test)
+		c.healthcheck = &healthcheck
+	}
+	c.waitStrategies = append([]WaitStrategy(nil), r.waitStrategies...)
+	c.networks = copyStrings(r.networks)
+	c.networkAliases = map[string][]string{}
+	for name, aliases := range r.networkAliases {
+		c.networkAliases[name] = copyStrings(aliases)
+	}
+	if r.resources.MemorySwappiness != nil {
+		swappiness := *r.resources.MemorySwappiness
+		c.resources.MemorySwappiness = &swappiness
+	}
+	if r.resources.OomKillDisable != nil {
+		disable := *r.resources.OomKillDisable
+		c.resources.OomKillDisable = &disable
+	}
+
+	c.exposedPorts = nat.PortSet{}
+	for port := range r.exposedPorts {
+		c.exposedPorts[port] = struct{}{}
+	}
+	c.portBindings = nat.PortMap{}
+	for port, bindings := range r.portBindings {
+		c.portBindings[port] = append([]nat.PortBinding(nil), bindings...)
+	}
+
+	opts := *r.opts
+	c.opts = &opts
+
+	// The clone manages its own container
+	c.resetRun()
+	c.client = nil
+	return &c
+}
+
+// Reset forgets the container of the last Start while keeping the
+// configuration, so that the next Start launches a fresh container. The
+// container should be stopped first, as Reset neither stops nor removes it.
+func (r *ContainerRunner) Reset() *ContainerRunner {
+	r.resetRun()
+	return r
+}
+
+// resetRun clears the state of the last Start
+func (r *ContainerRunner) resetRun() {
+	r.id = ""
+	r.removed = false
+	r.readiness = ReadinessResult{}
+	r.imagePulled = false
+	atomic.StoreInt32(&r.attempts, 0)
+	r.templateEnv = nil
+	r.imageCmd = nil
+}
+
+// copyStrings returns a copy of s that does not share its backing array
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string{}, s...)
+}
+
+// copyStringMap returns a copy of m
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,74 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+)
+
+// WithWorkdir sets the working directory of the container's command,
+// overriding the image's WORKDIR
+func (r *ContainerRunner) WithWorkdir(dir string) *ContainerRunner {
+	r.workdir = dir
+	return r
+}
+
+// WithCommand sets the command of the container, overriding the image's CMD.
+// The image's entrypoint is kept and receives the command as arguments.
+func (r *ContainerRunner) WithCommand(cmd ...string) *ContainerRunner {
+	r.cmd = cmd
+	return r
+}
+
+// WithEntrypoint sets the entrypoint of the container, overriding the image's
+// ENTRYPOINT. Note that Docker then also drops the image's CMD, so unless a
+// command is set with WithCommand the entrypoint runs without arguments. Use
+// WithEntrypointKeepingCmd to pass it the image's CMD instead.
+func (r *ContainerRunner) WithEntrypoint(entrypoint ...string) *ContainerRunner {
+	r.entrypoint = entrypoint
+	r.keepImageCmd = false
+	return r
+}
+
+// WithEntrypointKeepingCmd is WithEntrypoint, but the entrypoint receives the
+// image's CMD as arguments, unless a command is set with WithCommand. The CMD
+// is read from the image before the container is created.
+func (r *ContainerRunner) WithEntrypointKeepingCmd(entrypoint ...string) *ContainerRunner {
+	r.entrypoint = entrypoint
+	r.keepImageCmd = true
+	return r
+}
+
+// WithScript runs script with /bin/sh -c in workdir. The image's entrypoint
+// is overridden so that the shell runs the script directly, which requires the
+// image to ship /bin/sh.
+func (r *ContainerRunner) WithScript(workdir string, script string) *ContainerRunner {
+	// An entrypoint of [""] resets the one of the image
+	r.entrypoint = []string{""}
+	r.keepImageCmd = false
+	return r.WithWorkdir(workdir).WithCommand("/bin/sh", "-c", script)
+}
+
+// resolveImageCmd reads the CMD of the image when it must be kept despite an
+// overridden entrypoint, see WithEntrypointKeepingCmd
+func (e *ContainerRunner) resolveImageCmd(ctx context.Context) error {
+	e.imageCmd = nil
+	if !e.keepImageCmd || len(e.cmd) > 0 {
+		return nil
+	}
+	info, _, err := e.client.ImageInspectWithRaw(ctx, e.image)
+	if err != nil {
+		return fmt.Errorf("inspecting image: %w", err)
+	}
+	if info.Config != nil {
+		e.imageCmd = info.Config.Cmd
+	}
+	return nil
+}
+
+// command returns the command of the container
+func (e *ContainerRunner) command() []string {
+	if len(e.cmd) == 0 && e.keepImageCmd {
+		return e.imageCmd
+	}
+	return e.cmd
+}
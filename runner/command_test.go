@@ -0,0 +1,95 @@
+package runner
+
+import (
+	"context"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/strslice"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestWithScript(t *testing.T) {
+	runner := NewContainerRunner().
+		WithImage("alpine").
+		WithScript("/src", "make test")
+
+	config := runner.containerConfig()
+	require.Equal(t, "/src", config.WorkingDir)
+	require.Equal(t, strslice.StrSlice{"/bin/sh", "-c", "make test"}, config.Cmd)
+	require.Equal(t, strslice.StrSlice{""}, config.Entrypoint)
+}
+
+func TestWithEntrypoint(t *testing.T) {
+	newRunner := func() *ContainerRunner {
+		runner := NewContainerRunner().WithImage("redis")
+		runner.client = &mockClient{
+			imageInspect: func(image string) (types.ImageInspect, error) {
+				return types.ImageInspect{
+					ID:     image,
+					Config: &container.Config{Cmd: strslice.StrSlice{"redis-server"}},
+				}, nil
+			},
+		}
+		return runner
+	}
+
+	for _, tc := range []struct {
+		name       string
+		configure  func(r *ContainerRunner)
+		entrypoint strslice.StrSlice
+		cmd        strslice.StrSlice
+	}{
+		{
+			name:       "entrypoint only",
+			configure:  func(r *ContainerRunner) { r.WithEntrypoint("tini", "--") },
+			entrypoint: strslice.StrSlice{"tini", "--"},
+		},
+		{
+			name:      "cmd only",
+			configure: func(r *ContainerRunner) { r.WithCommand("redis-server", "--appendonly", "yes") },
+			cmd:       strslice.StrSlice{"redis-server", "--appendonly", "yes"},
+		},
+		{
+			name: "entrypoint and cmd",
+			configure: func(r *ContainerRunner) {
+				r.WithEntrypoint("tini", "--").WithCommand("redis-server", "--appendonly", "yes")
+			},
+			entrypoint: strslice.StrSlice{"tini", "--"},
+			cmd:        strslice.StrSlice{"redis-server", "--appendonly", "yes"},
+		},
+		{
+			name:       "entrypoint keeping the image cmd",
+			configure:  func(r *ContainerRunner) { r.WithEntrypointKeepingCmd("tini", "--") },
+			entrypoint: strslice.StrSlice{"tini", "--"},
+			cmd:        strslice.StrSlice{"redis-server"},
+		},
+		{
+			name: "entrypoint keeping the image cmd and cmd",
+			configure: func(r *ContainerRunner) {
+				r.WithEntrypointKeepingCmd("tini", "--").WithCommand("redis-server", "--appendonly", "yes")
+			},
+			entrypoint: strslice.StrSlice{"tini", "--"},
+			cmd:        strslice.StrSlice{"redis-server", "--appendonly", "yes"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := newRunner()
+			tc.configure(runner)
+			runner.WithPullPolicy(PullIfNotPresent)
+			require.NoError(t, runner.Start(context.Background()))
+
+			config := runner.containerConfig()
+			if tc.entrypoint == nil {
+				require.Empty(t, config.Entrypoint)
+			} else {
+				require.Equal(t, tc.entrypoint, config.Entrypoint)
+			}
+			if tc.cmd == nil {
+				require.Empty(t, config.Cmd)
+			} else {
+				require.Equal(t, tc.cmd, config.Cmd)
+			}
+		})
+	}
+}
@@ -0,0 +1,352 @@
+package runner
+
+import (
+	"fmt"
+	"github.com/docker/go-connections/nat"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseCompose reads the services of a docker-compose.yml file and returns a
+// runner for each of them, ordered so that every service comes after the ones
+// it depends on. Pass them to NewGroup to start and stop them together.
+//
+// Only this subset of compose is supported, any other field is an error:
+//
+//   - version and the top-level networks and volumes, which are ignored
+//   - services.<name>.image, which is used verbatim as with WithRawImage
+//   - services.<name>.ports, as "container", "host:container" or
+//     "ip:host:container", with an optional "/tcp" suffix
+//   - services.<name>.environment, as a mapping or as "KEY=value" entries,
+//     where variables without a value are taken from the current process
+//   - services.<name>.volumes, as "host:container" or "host:container:ro"
+//     bind mounts, where relative host paths are resolved against the
+//     directory of the file
+//   - services.<name>.depends_on, as a list or a mapping of service names,
+//     where the only supported condition is service_started; attach a wait
+//     strategy to the dependency to wait for it to be healthy instead
+//   - services.<name>.networks, as a list of names or a mapping from names to
+//     an optional "aliases" list; the networks must exist, see EnsureNetwork
+//
+// Containers are named after their service with WithNamePrefix, and like in
+// compose, other containers can reach a service by its name on every network
+// it is attached to.
+func ParseCompose(path string) ([]*ContainerRunner, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading compose file: %w", err)
+	}
+	var file composeFile
+	if err := yaml.UnmarshalStrict(b, &file); err != nil {
+		return nil, fmt.Errorf("parsing compose file %v: %w", path, err)
+	}
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("resolving compose directory: %w", err)
+	}
+	runners, err := composeRunners(file, dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid compose file %v: %w", path, err)
+	}
+	return runners, nil
+}
+
+// composeFile is the supported subset of a compose file
+type composeFile struct {
+	Version  string                    `yaml:"version"`
+	Services map[string]composeService `yaml:"services"`
+	Networks map[string]interface{}    `yaml:"networks"`
+	Volumes  map[string]interface{}    `yaml:"volumes"`
+}
+
+// composeService is the supported subset of a compose service
+type composeService struct {
+	Image       string              `yaml:"image"`
+	Ports       []string            `yaml:"ports"`
+	Environment composeEnvironment  `yaml:"environment"`
+	Volumes     []string            `yaml:"volumes"`
+	DependsOn   composeDependencies `yaml:"depends_on"`
+	Networks    composeNetworks     `yaml:"networks"`
+}
+
+// composeEnvironment is the environment of a service, which is either a
+// mapping or a list of "KEY=value" entries. Like in compose, a variable
+// without a value, "KEY" in a list or "KEY:" in a mapping, takes its value
+// from the environment of the current process and is left out if it is unset
+// there.
+type composeEnvironment map[string]string
+
+func (c *composeEnvironment) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = composeEnvironment{}
+	var m map[string]*string
+	if err := unmarshal(&m); err == nil {
+		for key, val := range m {
+			c.set(key, val)
+		}
+		return nil
+	}
+	var entries []string
+	if err := unmarshal(&entries); err != nil {
+		return fmt.Errorf("environment must be a mapping or a list of strings")
+	}
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts[0]) == 0 {
+			return fmt.Errorf("invalid environment variable %q", entry)
+		}
+		if len(parts) == 1 {
+			c.set(parts[0], nil)
+			continue
+		}
+		c.set(parts[0], &parts[1])
+	}
+	return nil
+}
+
+// set sets the variable key to val, or to its value in the environment of
+// the current process if val is nil
+func (c composeEnvironment) set(key string, val *string) {
+	if val != nil {
+		c[key] = *val
+	} else if host, ok := os.LookupEnv(key); ok {
+		c[key] = host
+	}
+}
+
+// composeDependencies are the names of the services a service depends on,
+// given as a list of names or a mapping from names to their condition. Groups
+// start their runners in order, which only implements service_started.
+type composeDependencies []string
+
+func (c *composeDependencies) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var names []string
+	if err := unmarshal(&names); err == nil {
+		*c = names
+		return nil
+	}
+	var m map[string]*struct {
+		Condition string `yaml:"condition"`
+	}
+	if err := unmarshal(&m); err != nil {
+		return err
+	}
+	for name, dep := range m {
+		if dep != nil && len(dep.Condition) > 0 && dep.Condition != "service_started" {
+			return fmt.Errorf("dependency %v: unsupported condition %v", name, dep.Condition)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	*c = names
+	return nil
+}
+
+// composeNetworks maps the networks a service is attached to to their extra
+// aliases, given as a list of names or a mapping from names to an optional
+// "aliases" list
+type composeNetworks map[string][]string
+
+func (c *composeNetworks) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = composeNetworks{}
+	var names []string
+	if err := unmarshal(&names); err == nil {
+		for _, name := range names {
+			(*c)[name] = nil
+		}
+		return nil
+	}
+	var m map[string]*struct {
+		Aliases []string `yaml:"aliases"`
+	}
+	if err := unmarshal(&m); err != nil {
+		return err
+	}
+	for name, network := range m {
+		(*c)[name] = nil
+		if network != nil {
+			(*c)[name] = network.Aliases
+		}
+	}
+	return nil
+}
+
+// composeRunners builds the runners of a parsed compose file
+func composeRunners(file composeFile, dir string) ([]*ContainerRunner, error) {
+	if len(file.Services) == 0 {
+		return nil, fmt.Errorf("services must be a non-empty mapping")
+	}
+
+	runners := map[string]*ContainerRunner{}
+	dependencies := map[string][]string{}
+	for name, service := range file.Services {
+		r, err := newComposeRunner(name, service, dir)
+		if err != nil {
+			return nil, fmt.Errorf("service %v: %w", name, err)
+		}
+		for _, dep := range service.DependsOn {
+			if _, ok := file.Services[dep]; !ok {
+				return nil, fmt.Errorf("service %v depends on unknown service %v", name, dep)
+			}
+		}
+		runners[name] = r
+		dependencies[name] = service.DependsOn
+	}
+
+	order, err := dependencyOrder(dependencies)
+	if err != nil {
+		return nil, err
+	}
+	ordered := make([]*ContainerRunner, 0, len(order))
+	for _, name := range order {
+		ordered = append(ordered, runners[name])
+	}
+	return ordered, nil
+}
+
+// newComposeRunner builds the runner of a service
+func newComposeRunner(name string, service composeService, dir string) (*ContainerRunner, error) {
+	if len(service.Image) == 0 {
+		return nil, fmt.Errorf("image is required")
+	}
+	// Like compose, pass the reference to Docker as is
+	r := NewContainerRunner().WithNamePrefix(name).WithRawImage(service.Image)
+	for _, p := range service.Ports {
+		containerPort, binding, err := composePort(p)
+		if err != nil {
+			return nil, err
+		}
+		r.WithPortBindings(containerPort, binding)
+	}
+	for _, key := range sortedKeys(service.Environment) {
+		r.WithEnvironmentVariable(key, service.Environment[key])
+	}
+	for _, v := range service.Volumes {
+		if err := composeVolume(r, v, dir); err != nil {
+			return nil, err
+		}
+	}
+	networks := make([]string, 0, len(service.Networks))
+	for network := range service.Networks {
+		networks = append(networks, network)
+	}
+	sort.Strings(networks)
+	for _, network := range networks {
+		// Like in compose, the service is reachable by its name
+		r.WithNetworkAlias(network, append([]string{name}, service.Networks[network]...)...)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r, nil
+}
+
+// composePort parses a port of the form "[[ip:]host:]container[/tcp]". A port
+// without a host port is published on a random one.
+func composePort(spec string) (int, nat.PortBinding, error) {
+	port := spec
+	if i := strings.Index(port, "/"); i >= 0 {
+		if port[i+1:] != "tcp" {
+			return 0, nat.PortBinding{}, fmt.Errorf("port %q: only tcp is supported", spec)
+		}
+		port = port[:i]
+	}
+	binding := nat.PortBinding{HostIP: DefaultHostAddress}
+	parts := strings.Split(port, ":")
+	switch len(parts) {
+	case 1:
+	case 2:
+		binding.HostPort = parts[0]
+	case 3:
+		binding.HostIP, binding.HostPort = parts[0], parts[1]
+	default:
+		return 0, nat.PortBinding{}, fmt.Errorf("invalid port %q", spec)
+	}
+	containerPort, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil || containerPort <= 0 {
+		return 0, nat.PortBinding{}, fmt.Errorf("invalid port %q", spec)
+	}
+	if len(binding.HostPort) > 0 {
+		if _, err := strconv.Atoi(binding.HostPort); err != nil {
+			return 0, nat.PortBinding{}, fmt.Errorf("invalid port %q", spec)
+		}
+	}
+	return containerPort, binding, nil
+}
+
+// composeVolume adds the bind mount "host:container[:ro]" to r
+func composeVolume(r *ContainerRunner, spec, dir string) error {
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return fmt.Errorf("invalid volume %q", spec)
+	}
+	host := parts[0]
+	if !strings.HasPrefix(host, "/") && !strings.HasPrefix(host, ".") && !strings.HasPrefix(host, "~") {
+		return fmt.Errorf("volume %q: named volumes are not supported", spec)
+	}
+	if strings.HasPrefix(host, "~") {
+		return fmt.Errorf("volume %q: home relative paths are not supported", spec)
+	}
+	if !filepath.IsAbs(host) {
+		host = filepath.Join(dir, host)
+	}
+	var opts []MountOption
+	if len(parts) == 3 {
+		switch parts[2] {
+		case "ro":
+			opts = append(opts, MountReadOnly())
+		case "rw":
+		default:
+			return fmt.Errorf("volume %q: unsupported mode %v", spec, parts[2])
+		}
+	}
+	r.WithVolume(host, parts[1], opts...)
+	return nil
+}
+
+// dependencyOrder sorts the services so that each comes after its
+// dependencies, and alphabetically otherwise
+func dependencyOrder(dependencies map[string][]string) ([]string, error) {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var order []string
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %v", strings.Join(append(path, name), " -> "))
+		}
+		state[name] = visiting
+		deps := append([]string(nil), dependencies[name]...)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	names := make([]string, 0, len(dependencies))
+	for name := range dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
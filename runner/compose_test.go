@@ -0,0 +1,141 @@
+package runner
+
+import (
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeCompose(t *testing.T, contents string) (string, string) {
+	dir, err := ioutil.TempDir("", "compose")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "docker-compose.yml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	return dir, path
+}
+
+func TestParseCompose(t *testing.T) {
+	dir, path := writeCompose(t, `
+version: "3.8"
+services:
+  api:
+    image: example/api:latest # the service under test
+    depends_on:
+      - db
+      - cache
+    ports: ["8080:80", "127.0.0.1:9090:90/tcp"]
+    environment:
+      DB_URL: "postgres://db:5432/app"
+      DEBUG: ""
+    networks:
+      backend:
+        aliases:
+          - web
+  db:
+    image: postgres:12
+    environment:
+    - POSTGRES_PASSWORD=secret
+    volumes:
+      - ./init:/docker-entrypoint-initdb.d:ro
+    ports:
+      - "5432"
+    networks:
+      - backend
+  cache:
+    image: 'redis'
+    depends_on:
+      db:
+        condition: service_started
+networks:
+  backend:
+`)
+
+	runners, err := ParseCompose(path)
+	require.NoError(t, err)
+	require.Len(t, runners, 3)
+	db, cache, api := runners[0], runners[1], runners[2]
+
+	require.Equal(t, "postgres:12", db.image)
+	require.Equal(t, "db", db.namePrefix)
+	require.Equal(t, []string{"POSTGRES_PASSWORD=secret"}, db.env)
+	require.Equal(t, filepath.Join(dir, "init"), db.mounts[0].Source)
+	require.Equal(t, "/docker-entrypoint-initdb.d", db.mounts[0].Target)
+	require.True(t, db.mounts[0].ReadOnly)
+	require.Equal(t, []nat.PortBinding{{HostIP: DefaultHostAddress}}, db.portBindings["5432"])
+	require.Equal(t, []string{"db"}, db.networkAliases["backend"])
+
+	require.Equal(t, "redis", cache.image)
+
+	require.Equal(t, "example/api:latest", api.image)
+	require.Equal(t, []string{"DB_URL=postgres://db:5432/app", "DEBUG="}, api.env)
+	require.Equal(t, []nat.PortBinding{{HostIP: DefaultHostAddress, HostPort: "8080"}}, api.portBindings["80"])
+	require.Equal(t, []nat.PortBinding{{HostIP: "127.0.0.1", HostPort: "9090"}}, api.portBindings["90"])
+	require.Equal(t, []string{"backend"}, api.networks)
+	require.Equal(t, []string{"api", "web"}, api.networkAliases["backend"])
+}
+
+func TestParseComposeErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		compose string
+	}{
+		{"unsupported field", "services:\n  db:\n    image: postgres\n    restart: always\n"},
+		{"missing image", "services:\n  db:\n    ports: [\"5432\"]\n"},
+		{"unknown dependency", "services:\n  db:\n    image: postgres\n    depends_on: [cache]\n"},
+		{"dependency cycle", "services:\n  a:\n    image: a\n    depends_on: [b]\n  b:\n    image: b\n    depends_on: [a]\n"},
+		{"named volume", "services:\n  db:\n    image: postgres\n    volumes: [\"data:/var/lib/postgresql/data\"]\n"},
+		{"udp port", "services:\n  dns:\n    image: coredns\n    ports: [\"53:53/udp\"]\n"},
+		{"bad indentation", "services:\n  db:\n    image: postgres\n      ports: []\n"},
+		{"unsupported top-level field", "configs: {}\nservices:\n  db:\n    image: postgres\n"},
+		{"no services", "version: \"3\"\n"},
+		{"long port syntax", "services:\n  db:\n    image: postgres\n    ports:\n      - target: 5432\n"},
+		{"unsupported network field", "services:\n  db:\n    image: postgres\n    networks:\n      backend:\n        ipv4_address: 10.0.0.2\n"},
+		{"healthy condition", "services:\n  api:\n    image: api\n    depends_on:\n      db:\n        condition: service_healthy\n  db:\n    image: postgres\n"},
+		{"completed condition", "services:\n  api:\n    image: api\n    depends_on:\n      db:\n        condition: service_completed_successfully\n  db:\n    image: postgres\n"},
+		{"dependency field", "services:\n  api:\n    image: api\n    depends_on:\n      db:\n        restart: true\n  db:\n    image: postgres\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, path := writeCompose(t, tc.compose)
+			_, err := ParseCompose(path)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestParseComposeFlowStyle(t *testing.T) {
+	_, path := writeCompose(t, `{
+  "version": "3.8",
+  "services": {
+    "api": {
+      "image": "example/api",
+      "ports": [8080],
+      "environment": {"COMMENT": "not # a comment", "QUOTED": 'it''s'},
+      "depends_on": {"db": {"condition": "service_started"}},
+      "networks": {"backend": {"aliases": [web, "www"]}, "frontend": null}
+    },
+    "db": {"image": "postgres", "environment": ["A=1", "B=x=y", "COMPOSE_TEST_HOST", "COMPOSE_TEST_UNSET"]},
+    "cache": {"image": "redis", "environment": {"COMPOSE_TEST_HOST": null, "COMPOSE_TEST_UNSET": null, "EMPTY": ""}}
+  }
+}`)
+	// Variables without a value come from the current process
+	defer os.Unsetenv("COMPOSE_TEST_HOST")
+	require.NoError(t, os.Setenv("COMPOSE_TEST_HOST", "from host"))
+	os.Unsetenv("COMPOSE_TEST_UNSET")
+
+	runners, err := ParseCompose(path)
+	require.NoError(t, err)
+	require.Len(t, runners, 3)
+	db, api, cache := runners[0], runners[1], runners[2]
+
+	require.Equal(t, []string{"A=1", "B=x=y", "COMPOSE_TEST_HOST=from host"}, db.env)
+	require.Equal(t, []string{"COMPOSE_TEST_HOST=from host", "EMPTY="}, cache.env)
+	require.Equal(t, []nat.PortBinding{{HostIP: DefaultHostAddress}}, api.portBindings["8080"])
+	require.Equal(t, []string{"COMMENT=not # a comment", "QUOTED=it's"}, api.env)
+	require.Equal(t, []string{"backend", "frontend"}, api.networks)
+	require.Equal(t, []string{"api", "web", "www"}, api.networkAliases["backend"])
+	require.Equal(t, []string{"api"}, api.networkAliases["frontend"])
+}
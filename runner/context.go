@@ -0,0 +1,32 @@
+package runner
+
+import (
+	"context"
+	"errors"
+)
+
+// WithContext sets the base context of the runner, which StopDefault and
+// StartForTest use in place of an explicit one. It defaults to
+// context.Background(). Start, Stop and the other methods keep using the
+// context passed to them.
+func (r *ContainerRunner) WithContext(ctx context.Context) *ContainerRunner {
+	if ctx == nil {
+		r.setErr(errors.New("context must not be nil"))
+		return r
+	}
+	r.baseCtx = ctx
+	return r
+}
+
+// StopDefault is Stop with the context set with WithContext
+func (e *ContainerRunner) StopDefault(opts ...StopOption) error {
+	return e.Stop(e.baseContext(), opts...)
+}
+
+// baseContext returns the context set with WithContext
+func (e *ContainerRunner) baseContext() context.Context {
+	if e.baseCtx == nil {
+		return context.Background()
+	}
+	return e.baseCtx
+}
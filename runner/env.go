@@ -0,0 +1,192 @@
+package runner
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/docker/go-connections/nat"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// WithEnvFile reads environment variables from one or more env files, using
+// the same format as docker's --env-file and docker-compose's env_file:
+// one KEY=VALUE per line, blank lines and lines starting with # are ignored,
+// and a bare KEY takes its value from the host environment (and is skipped
+// if it isn't set there). Values are used verbatim, quotes included.
+//
+// Variables are deduplicated by key with the following precedence, lowest
+// first:
+//
+//  1. env files, in the order they were passed (later files override
+//     earlier ones, including across multiple WithEnvFile calls)
+//  2. WithEnvironmentVariable, which overrides every env file
+func (r *ContainerRunner) WithEnvFile(paths ...string) *ContainerRunner {
+	for _, path := range paths {
+		env, err := readEnvFile(path)
+		if err != nil {
+			r.setErr(err)
+			return r
+		}
+		r.fileEnv = append(r.fileEnv, env...)
+	}
+	r.env = mergeEnv(r.fileEnv, r.explicitEnv)
+	return r
+}
+
+// readEnvFile parses the KEY=VALUE entries of the env file at path
+func readEnvFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening env file: %w", err)
+	}
+	defer f.Close()
+
+	var env []string
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimLeft(scanner.Text(), " \t")
+		if len(text) == 0 || strings.HasPrefix(text, "#") {
+			continue
+		}
+		parts := strings.SplitN(text, "=", 2)
+		key := parts[0]
+		if len(key) == 0 || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("env file %v line %v: invalid variable name %q", path, line, key)
+		}
+		if len(parts) == 1 {
+			val, ok := os.LookupEnv(key)
+			if !ok {
+				continue
+			}
+			env = append(env, fmt.Sprintf("%v=%v", key, val))
+			continue
+		}
+		env = append(env, text)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading env file %v: %w", path, err)
+	}
+	return env, nil
+}
+
+// mergeEnv merges KEY=VALUE lists into one, where a key in a later entry
+// overrides the same key in any earlier entry. The position of each key is
+// the position where it was first defined.
+func mergeEnv(sources ...[]string) []string {
+	merged := []string{}
+	index := map[string]int{}
+	for _, source := range sources {
+		for _, kv := range source {
+			key := strings.SplitN(kv, "=", 2)[0]
+			if i, ok := index[key]; ok {
+				merged[i] = kv
+				continue
+			}
+			index[key] = len(merged)
+			merged = append(merged, kv)
+		}
+	}
+	return merged
+}
+
+// envTemplate is an environment variable whose value is resolved on Start
+type envTemplate struct {
+	key  string
+	tmpl *template.Template
+}
+
+// EnvTemplateData is the data available to the templates of WithEnvTemplate
+type EnvTemplateData struct {
+	// Name is the name of the container, including one generated by
+	// WithNamePrefix
+	Name string
+	// Image is the image of the container
+	Image string
+	// RunID is the RunID of the current process
+	RunID string
+
+	portBindings map[nat.Port][]nat.PortBinding
+}
+
+// HostPort returns the host port that the given container port is bound to,
+// e.g. {{.HostPort 5432}}. Without a port, e.g. {{.HostPort}}, it returns
+// the host port of the only published port. Ports bound to a random host
+// port cannot be resolved, as that is only known after the container started.
+func (d EnvTemplateData) HostPort(containerPort ...int) (string, error) {
+	var port nat.Port
+	switch len(containerPort) {
+	case 0:
+		if len(d.portBindings) != 1 {
+			return "", fmt.Errorf("HostPort requires a container port when %v ports are published", len(d.portBindings))
+		}
+		for p := range d.portBindings {
+			port = p
+		}
+	case 1:
+		port = nat.Port(strconv.Itoa(containerPort[0]))
+	default:
+		return "", errors.New("HostPort takes at most one container port")
+	}
+	for _, b := range d.portBindings[port] {
+		if len(b.HostPort) > 0 && b.HostPort != "0" {
+			return b.HostPort, nil
+		}
+	}
+	if _, ok := d.portBindings[port]; ok {
+		return "", fmt.Errorf("port %v is bound to a random host port, which is not known before start", port.Port())
+	}
+	return "", fmt.Errorf("port %v is not published", port.Port())
+}
+
+// WithEnvTemplate sets an environment variable whose value is a text/template
+// resolved on Start, right before the container is created, with
+// EnvTemplateData, e.g. "http://localhost:{{.HostPort 8080}}" or
+// "{{.Name}}.internal". Docker cannot change the environment of a created
+// container, so only values known before it is created can be used. Templated
+// variables take precedence over WithEnvironmentVariable and env files.
+func (r *ContainerRunner) WithEnvTemplate(key, value string) *ContainerRunner {
+	tmpl, err := template.New(key).Option("missingkey=error").Parse(value)
+	if err != nil {
+		r.setErr(fmt.Errorf("parsing template of environment variable %v: %w", key, err))
+		return r
+	}
+	r.envTemplates = append(r.envTemplates, envTemplate{key: key, tmpl: tmpl})
+	return r
+}
+
+// resolveEnvTemplates executes the templates of WithEnvTemplate
+func (e *ContainerRunner) resolveEnvTemplates() error {
+	e.templateEnv = nil
+	if len(e.envTemplates) == 0 {
+		return nil
+	}
+	data := EnvTemplateData{
+		Name:         e.name,
+		Image:        e.image,
+		RunID:        RunID,
+		portBindings: e.portBindings,
+	}
+	if e.noNetwork {
+		data.portBindings = nil
+	}
+	for _, t := range e.envTemplates {
+		var b strings.Builder
+		if err := t.tmpl.Execute(&b, data); err != nil {
+			return fmt.Errorf("resolving environment variable %v: %w", t.key, err)
+		}
+		e.templateEnv = append(e.templateEnv, fmt.Sprintf("%v=%v", t.key, b.String()))
+	}
+	return nil
+}
+
+// containerEnv returns the environment of the container, including the
+// resolved templates
+func (e *ContainerRunner) containerEnv() []string {
+	if len(e.templateEnv) == 0 {
+		return e.env
+	}
+	return mergeEnv(e.env, e.templateEnv)
+}
@@ -0,0 +1,62 @@
+package runner
+
+import (
+	"context"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithEnvFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "env")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	base := filepath.Join(dir, "base.env")
+	require.NoError(t, ioutil.WriteFile(base, []byte("# defaults\nHOST=localhost\nPORT=5432\n\nUSER=postgres\n"), 0644))
+	local := filepath.Join(dir, "local.env")
+	require.NoError(t, ioutil.WriteFile(local, []byte("PORT=15432\nDEBUG=1\n"), 0644))
+
+	runner := NewContainerRunner().
+		WithEnvironmentVariable("USER", "admin").
+		WithEnvFile(base, local)
+
+	require.NoError(t, runner.err)
+	require.Equal(t, []string{"HOST=localhost", "PORT=15432", "USER=admin", "DEBUG=1"}, runner.env)
+}
+
+func TestWithEnvFileMissing(t *testing.T) {
+	runner := NewContainerRunner().WithEnvFile("does-not-exist.env")
+	require.Error(t, runner.err)
+}
+
+func TestWithEnvTemplate(t *testing.T) {
+	runner := NewContainerRunner().
+		WithImage("keycloak").
+		WithNamePrefix("auth").
+		WithPorts(8080).
+		WithPortBindings(9990, nat.PortBinding{HostIP: DefaultHostAddress}).
+		WithEnvironmentVariable("KC_HOSTNAME_URL", "http://localhost").
+		WithEnvTemplate("KC_HOSTNAME_URL", "http://localhost:{{.HostPort 8080}}").
+		WithEnvTemplate("KC_HOSTNAME", "{{.Name}}.internal")
+	runner.client = &mockClient{}
+	require.NoError(t, runner.Start(context.Background()))
+	require.Equal(t, []string{
+		"KC_HOSTNAME_URL=http://localhost:8080",
+		"KC_HOSTNAME=" + runner.Name() + ".internal",
+	}, runner.containerConfig().Env)
+
+	// The host port of a random binding is only known after start
+	runner = NewContainerRunner().
+		WithImage("keycloak").
+		WithPortBindings(9990, nat.PortBinding{HostIP: DefaultHostAddress}).
+		WithEnvTemplate("MANAGEMENT_PORT", "{{.HostPort}}")
+	runner.client = &mockClient{}
+	require.Error(t, runner.Start(context.Background()))
+
+	runner = NewContainerRunner().WithEnvTemplate("BROKEN", "{{.HostPort")
+	require.Error(t, runner.err)
+}
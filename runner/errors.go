@@ -0,0 +1,124 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrImageNotFound = errors.New("image not found")
+	ErrNameConflict  = errors.New("container name already in use")
+	ErrPortInUse     = errors.New("host port already in use")
+	ErrPathNotFound  = errors.New("path not found in container")
+	ErrIsDirectory   = errors.New("path is a directory")
+)
+
+// ExitError is returned when a container that was run attached exits with a
+// non-zero exit code
+type ExitError struct {
+	Code int64
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("container exited with code %v", e.Code)
+}
+
+// ImageNotFoundError is returned when the image to run does not exist in the
+// registry or, when pulling is disabled, on the daemon. It matches
+// ErrImageNotFound.
+type ImageNotFoundError struct {
+	Reference string
+	Err       error
+}
+
+func (e *ImageNotFoundError) Error() string {
+	return fmt.Sprintf("image %v not found", e.Reference)
+}
+
+func (e *ImageNotFoundError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ImageNotFoundError) Is(target error) bool {
+	return target == ErrImageNotFound
+}
+
+// classifyImageError classifies err like classifyError, turning errors that
+// denote a missing image into an *ImageNotFoundError for reference
+func classifyImageError(reference string, err error) error {
+	err = classifyError(err)
+	if errors.Is(err, ErrImageNotFound) {
+		return &ImageNotFoundError{Reference: reference, Err: err}
+	}
+	return err
+}
+
+// classifiedError is a Docker API error that also matches one of the
+// package's sentinel errors with errors.Is
+type classifiedError struct {
+	kind error
+	err  error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+func (e *classifiedError) Is(target error) bool {
+	return target == e.kind
+}
+
+// errorClasses maps fragments of Docker daemon error messages to the sentinel
+// error they denote
+var errorClasses = []struct {
+	kind      error
+	fragments []string
+}{
+	{
+		kind: ErrImageNotFound,
+		fragments: []string{
+			"manifest unknown",
+			"repository does not exist",
+			"no such image",
+			"not found: manifest",
+		},
+	}, {
+		kind:      ErrNameConflict,
+		fragments: []string{"is already in use by container"},
+	}, {
+		kind: ErrPortInUse,
+		fragments: []string{
+			"port is already allocated",
+			"address already in use",
+		},
+	}, {
+		kind:      ErrPathNotFound,
+		fragments: []string{"no such container:path", "could not find the file"},
+	},
+}
+
+// classifyError wraps err so that it matches the sentinel error describing it,
+// if any. The message of err is left untouched.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := strings.ToLower(err.Error())
+	for _, class := range errorClasses {
+		for _, fragment := range class.fragments {
+			if strings.Contains(msg, fragment) {
+				return &classifiedError{kind: class.kind, err: err}
+			}
+		}
+	}
+	// Docker reports missing manifests as "manifest for <ref> not found"
+	if strings.Contains(msg, "manifest for") && strings.Contains(msg, "not found") {
+		return &classifiedError{kind: ErrImageNotFound, err: err}
+	}
+	return err
+}
@@ -0,0 +1,61 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestClassifyError(t *testing.T) {
+	var testCases = []struct {
+		name string
+		in   string
+		out  error
+	}{
+		{
+			name: "missing tag",
+			in:   "Error response from daemon: manifest for redis:nonexistent not found",
+			out:  ErrImageNotFound,
+		}, {
+			name: "missing repository",
+			in:   "Error response from daemon: pull access denied for nope, repository does not exist or may require 'docker login'",
+			out:  ErrImageNotFound,
+		}, {
+			name: "name conflict",
+			in:   `Error response from daemon: Conflict. The container name "/mongo" is already in use by container "abc"`,
+			out:  ErrNameConflict,
+		}, {
+			name: "port in use",
+			in:   "Error response from daemon: driver failed programming external connectivity: Bind for 127.0.0.1:27017 failed: port is already allocated",
+			out:  ErrPortInUse,
+		}, {
+			name: "unclassified",
+			in:   "Error response from daemon: something else",
+			out:  nil,
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			in := errors.New(c.in)
+			err := fmt.Errorf("starting container: %w", classifyError(in))
+			require.True(t, errors.Is(err, in))
+			for _, sentinel := range []error{ErrImageNotFound, ErrNameConflict, ErrPortInUse} {
+				require.Equal(t, sentinel == c.out, errors.Is(err, sentinel))
+			}
+		})
+	}
+}
+
+func TestClassifyImageError(t *testing.T) {
+	in := errors.New("Error response from daemon: manifest for docker.io/library/redis:nonexistent not found: manifest unknown")
+	err := classifyImageError("docker.io/library/redis:nonexistent", in)
+	require.EqualError(t, err, "image docker.io/library/redis:nonexistent not found")
+	require.True(t, errors.Is(err, ErrImageNotFound))
+	require.True(t, errors.Is(err, in))
+
+	var notFound *ImageNotFoundError
+	require.True(t, errors.As(fmt.Errorf("pulling image: %w", err), &notFound))
+	require.Equal(t, "docker.io/library/redis:nonexistent", notFound.Reference)
+}
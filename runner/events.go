@@ -0,0 +1,51 @@
+package runner
+
+import (
+	"context"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// Events subscribes to the Docker events (die, oom, health_status, ...) of the
+// container that was started using Start. Both channels are closed once ctx is
+// cancelled or the stream fails, in which case the error is sent on the error
+// channel before it is closed.
+func (e *ContainerRunner) Events(ctx context.Context) (<-chan events.Message, <-chan error) {
+	messages := make(chan events.Message)
+	errs := make(chan error, 1)
+
+	// If we don't have a container id
+	if len(e.id) == 0 {
+		close(messages)
+		errs <- ErrNoContainerId
+		close(errs)
+		return messages, errs
+	}
+
+	args := filters.NewArgs()
+	args.Add("container", e.id)
+	in, inErrs := e.client.Events(ctx, types.EventsOptions{Filters: args})
+
+	go func() {
+		defer close(errs)
+		defer close(messages)
+		for {
+			select {
+			case m := <-in:
+				select {
+				case messages <- m:
+				case <-ctx.Done():
+					errs <- ctx.Err()
+					return
+				}
+			case err, ok := <-inErrs:
+				if ok && err != nil {
+					errs <- err
+				}
+				return
+			}
+		}
+	}()
+	return messages, errs
+}
@@ -0,0 +1,65 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+)
+
+func TestEvents(t *testing.T) {
+	runner := NewContainerRunner()
+	messages, errs := runner.Events(context.Background())
+	_, ok := <-messages
+	require.False(t, ok)
+	require.Equal(t, ErrNoContainerId, <-errs)
+
+	runner.id = "id"
+	runner.client = &mockClient{
+		events: func(options types.EventsOptions) (<-chan events.Message, <-chan error) {
+			require.Equal(t, []string{"id"}, options.Filters.Get("container"))
+			// Like the client, the messages are never closed and the
+			// stream ends with an error
+			in := make(chan events.Message)
+			inErrs := make(chan error, 1)
+			go func() {
+				defer close(inErrs)
+				in <- events.Message{Status: "health_status: healthy", ID: "id"}
+				in <- events.Message{Status: "die", ID: "id"}
+				inErrs <- io.ErrUnexpectedEOF
+			}()
+			return in, inErrs
+		},
+	}
+	messages, errs = runner.Events(context.Background())
+	var statuses []string
+	for m := range messages {
+		statuses = append(statuses, m.Status)
+	}
+	require.Equal(t, []string{"health_status: healthy", "die"}, statuses)
+	require.True(t, errors.Is(<-errs, io.ErrUnexpectedEOF))
+	_, ok = <-errs
+	require.False(t, ok)
+}
+
+func TestEventsCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	runner := NewContainerRunner()
+	runner.id = "id"
+	runner.client = &mockClient{
+		events: func(options types.EventsOptions) (<-chan events.Message, <-chan error) {
+			in := make(chan events.Message, 1)
+			in <- events.Message{Status: "oom", ID: "id"}
+			return in, make(chan error)
+		},
+	}
+	messages, errs := runner.Events(ctx)
+	// Nobody reads the message while ctx is cancelled
+	cancel()
+	require.Equal(t, context.Canceled, <-errs)
+	for range messages {
+	}
+}
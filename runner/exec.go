@@ -0,0 +1,182 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"io"
+	"sync"
+	"time"
+)
+
+// ExecStream runs cmd inside the running container and streams its stdout and
+// stderr while it runs, which allows tailing long-running commands. wait
+// blocks until the command exits and returns its exit code. Output is
+// buffered until it is read, so reading only one of the streams is fine.
+// Closing either stream, or cancelling ctx, detaches from the command without
+// stopping it; the streams then fail with io.ErrClosedPipe or ctx.Err().
+func (e *ContainerRunner) ExecStream(ctx context.Context, cmd []string) (stdout, stderr io.ReadCloser, wait func() (int, error), err error) {
+	// If we don't have a container id
+	if len(e.id) == 0 {
+		return nil, nil, nil, ErrNoContainerId
+	}
+
+	config := types.ExecConfig{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	}
+	exec, err := e.client.ContainerExecCreate(ctx, e.id, config)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("creating exec: %w", err)
+	}
+	resp, err := e.client.ContainerExecAttach(ctx, exec.ID, config)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("attaching to exec: %w", err)
+	}
+
+	outBuf, errBuf := newStreamBuffer(), newStreamBuffer()
+	detached := make(chan struct{})
+	var once sync.Once
+	detach := func(err error) {
+		once.Do(func() {
+			close(detached)
+			outBuf.closeWithError(err)
+			errBuf.closeWithError(err)
+			// Unblocks the copy below
+			resp.Close()
+		})
+	}
+	outBuf.detach = detach
+	errBuf.detach = detach
+
+	copied := make(chan error, 1)
+	go func() {
+		defer resp.Close()
+		_, err := stdcopy.StdCopy(outBuf, errBuf, resp.Reader)
+		select {
+		case <-detached:
+			err = io.ErrClosedPipe
+		default:
+		}
+		outBuf.closeWithError(err)
+		errBuf.closeWithError(err)
+		copied <- err
+	}()
+	go func() {
+		select {
+		case <-ctx.Done():
+			detach(ctx.Err())
+		case <-outBuf.done:
+		}
+	}()
+
+	wait = func() (int, error) {
+		select {
+		case err := <-copied:
+			if err != nil && err != io.ErrClosedPipe {
+				return -1, fmt.Errorf("copying exec output: %w", err)
+			}
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			return -1, err
+		}
+		return e.execExitCode(ctx, exec.ID)
+	}
+	return outBuf, errBuf, wait, nil
+}
+
+// execExitCode returns the exit code of the exec, waiting for it to exit as
+// its output can end slightly before the daemon reports the exit
+func (e *ContainerRunner) execExitCode(ctx context.Context, id string) (int, error) {
+	ticker := time.NewTicker(DefaultExitPollInterval)
+	defer ticker.Stop()
+	for {
+		info, err := e.client.ContainerExecInspect(ctx, id)
+		if err != nil {
+			return -1, fmt.Errorf("inspecting exec: %w", err)
+		}
+		if !info.Running {
+			return info.ExitCode, nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return -1, ctx.Err()
+		}
+	}
+}
+
+// streamBuffer is an in-memory pipe whose writes never block, so that output
+// nobody reads doesn't stall the copy of the other stream
+type streamBuffer struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	buf  bytes.Buffer
+	// err is returned by Read once buf is drained, and done is closed when
+	// it is set
+	err  error
+	done chan struct{}
+	// detach is called when the reader closes the stream
+	detach func(error)
+}
+
+func newStreamBuffer() *streamBuffer {
+	b := &streamBuffer{done: make(chan struct{})}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+func (b *streamBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return 0, io.ErrClosedPipe
+	}
+	b.cond.Broadcast()
+	return b.buf.Write(p)
+}
+
+func (b *streamBuffer) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.buf.Len() == 0 && b.err == nil {
+		b.cond.Wait()
+	}
+	if b.buf.Len() > 0 {
+		return b.buf.Read(p)
+	}
+	return 0, b.err
+}
+
+// Close discards the buffered output, fails later reads with io.ErrClosedPipe
+// and detaches from the writer
+func (b *streamBuffer) Close() error {
+	b.mu.Lock()
+	b.buf.Reset()
+	b.mu.Unlock()
+	b.closeWithError(io.ErrClosedPipe)
+	if b.detach != nil {
+		b.detach(io.ErrClosedPipe)
+	}
+	return nil
+}
+
+// closeWithError makes Read return err, or io.EOF if err is nil, once the
+// buffered output is drained. Only the first call has an effect.
+func (b *streamBuffer) closeWithError(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return
+	}
+	if err == nil {
+		err = io.EOF
+	}
+	b.err = err
+	close(b.done)
+	b.cond.Broadcast()
+}
@@ -0,0 +1,118 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+)
+
+func TestExecStream(t *testing.T) {
+	server, conn := net.Pipe()
+	go func() {
+		stdcopy.NewStdWriter(server, stdcopy.Stdout).Write([]byte("tick\n"))
+		stdcopy.NewStdWriter(server, stdcopy.Stderr).Write([]byte("warning\n"))
+		server.Close()
+	}()
+
+	runner := NewContainerRunner()
+	runner.id = "id"
+	runner.client = &mockClient{
+		execAttach: func(config types.ExecConfig) (types.HijackedResponse, error) {
+			require.Equal(t, []string{"tail", "-f", "/var/log/app.log"}, config.Cmd)
+			return types.HijackedResponse{Conn: conn, Reader: bufio.NewReader(conn)}, nil
+		},
+		execInspect: func(id string) (types.ContainerExecInspect, error) {
+			return types.ContainerExecInspect{ExitCode: 3}, nil
+		},
+	}
+
+	stdout, stderr, wait, err := runner.ExecStream(context.Background(), []string{"tail", "-f", "/var/log/app.log"})
+	require.NoError(t, err)
+	errc := make(chan []byte)
+	go func() {
+		b, _ := ioutil.ReadAll(stderr)
+		errc <- b
+	}()
+	out, err := ioutil.ReadAll(stdout)
+	require.NoError(t, err)
+	require.Equal(t, "tick\n", string(out))
+	require.Equal(t, "warning\n", string(<-errc))
+
+	code, err := wait()
+	require.NoError(t, err)
+	require.Equal(t, 3, code)
+}
+
+func TestExecStreamStdoutOnly(t *testing.T) {
+	server, conn := net.Pipe()
+	go func() {
+		// More stderr output than any pipe would hold before stdout
+		stdcopy.NewStdWriter(server, stdcopy.Stderr).Write(bytes.Repeat([]byte("warning\n"), 1<<16))
+		stdcopy.NewStdWriter(server, stdcopy.Stdout).Write([]byte("done\n"))
+		server.Close()
+	}()
+
+	runner := NewContainerRunner()
+	runner.id = "id"
+	runner.client = &mockClient{
+		execAttach: func(config types.ExecConfig) (types.HijackedResponse, error) {
+			return types.HijackedResponse{Conn: conn, Reader: bufio.NewReader(conn)}, nil
+		},
+		execInspect: func(id string) (types.ContainerExecInspect, error) {
+			return types.ContainerExecInspect{}, nil
+		},
+	}
+
+	stdout, stderr, wait, err := runner.ExecStream(context.Background(), []string{"build"})
+	require.NoError(t, err)
+	out, err := ioutil.ReadAll(stdout)
+	require.NoError(t, err)
+	require.Equal(t, "done\n", string(out))
+	code, err := wait()
+	require.NoError(t, err)
+	require.Equal(t, 0, code)
+	require.NoError(t, stderr.Close())
+}
+
+func TestExecStreamCancel(t *testing.T) {
+	server, conn := net.Pipe()
+	serverClosed := make(chan struct{})
+	go func() {
+		stdcopy.NewStdWriter(server, stdcopy.Stdout).Write([]byte("tick\n"))
+		// Blocks until the client side of the connection is closed
+		ioutil.ReadAll(server)
+		close(serverClosed)
+	}()
+
+	runner := NewContainerRunner()
+	runner.id = "id"
+	runner.client = &mockClient{
+		execAttach: func(config types.ExecConfig) (types.HijackedResponse, error) {
+			return types.HijackedResponse{Conn: conn, Reader: bufio.NewReader(conn)}, nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stdout, stderr, wait, err := runner.ExecStream(ctx, []string{"tail", "-f", "/var/log/app.log"})
+	require.NoError(t, err)
+	b := make([]byte, 5)
+	_, err = io.ReadFull(stdout, b)
+	require.NoError(t, err)
+	require.Equal(t, "tick\n", string(b))
+
+	cancel()
+	_, err = wait()
+	require.Equal(t, context.Canceled, err)
+	_, err = ioutil.ReadAll(stdout)
+	require.Equal(t, context.Canceled, err)
+	_, err = ioutil.ReadAll(stderr)
+	require.Equal(t, context.Canceled, err)
+	<-serverClosed
+}
@@ -0,0 +1,112 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Group manages several runners as a unit, such as the dependencies of a test
+// suite
+type Group struct {
+	runners []*ContainerRunner
+}
+
+// NewGroup builds a group of runners. Runners are started in the order they
+// were added.
+func NewGroup(runners ...*ContainerRunner) *Group {
+	return &Group{runners: runners}
+}
+
+// Add adds runners to the group
+func (g *Group) Add(runners ...*ContainerRunner) *Group {
+	g.runners = append(g.runners, runners...)
+	return g
+}
+
+// Runners returns the runners of the group in the order they are started
+func (g *Group) Runners() []*ContainerRunner {
+	return append([]*ContainerRunner(nil), g.runners...)
+}
+
+// Start starts the runners one after another, in the order they were added,
+// and returns the first error. Runners that were started before the error are
+// left running so that the caller can inspect or Stop them.
+func (g *Group) Start(ctx context.Context) error {
+	for _, r := range g.runners {
+		if err := r.Start(ctx); err != nil {
+			return fmt.Errorf("starting %v: %w", r.displayName(), err)
+		}
+	}
+	return nil
+}
+
+// Stop stops every runner of the group concurrently, so a deadline on ctx is
+// shared by all of them. Every runner gets a stop attempt even if others fail,
+// and all failures are returned together as a MultiError.
+func (g *Group) Stop(ctx context.Context, opts ...StopOption) error {
+	errs := make([]error, len(g.runners))
+	var wg sync.WaitGroup
+	for i, r := range g.runners {
+		wg.Add(1)
+		go func(i int, r *ContainerRunner) {
+			defer wg.Done()
+			if err := r.Stop(ctx, opts...); err != nil {
+				errs[i] = fmt.Errorf("stopping %v: %w", r.displayName(), err)
+			}
+		}(i, r)
+	}
+	wg.Wait()
+
+	return newMultiError(errs)
+}
+
+// displayName returns the name of the container for messages, falling back
+// to its id and image
+func (e *ContainerRunner) displayName() string {
+	switch {
+	case len(e.name) > 0:
+		return e.name
+	case len(e.id) > 0:
+		return e.id
+	}
+	return e.image
+}
+
+// MultiError is a list of errors that occurred together
+type MultiError []error
+
+func (m MultiError) Error() string {
+	msgs := make([]string, len(m))
+	for i, err := range m {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%v errors occurred: %v", len(m), strings.Join(msgs, "; "))
+}
+
+// newMultiError returns the non-nil errors of errs as a MultiError, or nil if
+// there are none
+func newMultiError(errs []error) error {
+	var failures MultiError
+	for _, err := range errs {
+		if err != nil {
+			failures = append(failures, err)
+		}
+	}
+	if len(failures) > 0 {
+		return failures
+	}
+	return nil
+}
+
+// Is returns true if any of the errors matches target
+func (m MultiError) Is(target error) bool {
+	for _, err := range m {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestGroupStop(t *testing.T) {
+	failing := errors.New("daemon hiccup")
+	runners := make([]*ContainerRunner, 3)
+	stopped := make([]bool, 3)
+	for i, name := range []string{"mongo", "redis", "postgres"} {
+		i := i
+		runners[i] = NewContainerRunner().WithName(name).WithImage(name)
+		runners[i].client = &mockClient{
+			containerStop: func(ctx context.Context, id string) error {
+				stopped[i] = true
+				if i != 1 {
+					return failing
+				}
+				return nil
+			},
+		}
+	}
+	group := NewGroup(runners...)
+	require.NoError(t, group.Start(context.Background()))
+
+	err := group.Stop(context.Background())
+	require.Equal(t, []bool{true, true, true}, stopped)
+
+	var failures MultiError
+	require.True(t, errors.As(err, &failures))
+	require.Len(t, failures, 2)
+	require.Contains(t, err.Error(), "stopping mongo")
+	require.Contains(t, err.Error(), "stopping postgres")
+	require.True(t, errors.Is(err, failing))
+}
@@ -0,0 +1,221 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// PullPolicy decides whether Start pulls the image
+type PullPolicy string
+
+const (
+	// PullAlways pulls the image on every Start, which is the default
+	PullAlways PullPolicy = "always"
+	// PullIfNotPresent only pulls the image if the daemon doesn't have it
+	PullIfNotPresent PullPolicy = "missing"
+	// PullNever never pulls the image and fails if the daemon doesn't have
+	// it
+	PullNever PullPolicy = "never"
+)
+
+// WithPullPolicy sets whether Start pulls the image, see PullPolicy
+func (r *ContainerRunner) WithPullPolicy(policy PullPolicy) *ContainerRunner {
+	switch policy {
+	case PullAlways, PullIfNotPresent, PullNever:
+		r.pullPolicy = policy
+	default:
+		r.setErr(fmt.Errorf("invalid pull policy %q", policy))
+	}
+	return r
+}
+
+// ImageWasPulled returns whether the last Start actually pulled the image, as
+// opposed to using an image that was already present
+func (r *ContainerRunner) ImageWasPulled() bool {
+	return r.imagePulled
+}
+
+// WithImageTarball loads the image from a tarball created by `docker save`
+// instead of pulling it from a registry, which allows fully offline runs. The
+// image reference is taken from the tarball, so WithImage is not needed.
+func (r *ContainerRunner) WithImageTarball(path string) *ContainerRunner {
+	r.imageTarball = path
+	return r
+}
+
+// PullImage makes the image available to the daemon according to the pull
+// policy, using the runner's registry credentials, without starting a
+// container. This allows pulling images up front, e.g. while warming up CI,
+// and keeping Start fast by combining it with PullIfNotPresent.
+func (e *ContainerRunner) PullImage(ctx context.Context) error {
+	if e.err != nil {
+		return fmt.Errorf("invalid runner configuration: %w", e.err)
+	}
+	if err := e.Ping(ctx); err != nil {
+		return err
+	}
+	return e.ensureImage(ctx)
+}
+
+// PullImages pulls the given images concurrently, as WithImage would resolve
+// them, and returns the failures as a MultiError
+func PullImages(ctx context.Context, images ...string) error {
+	runners := make([]*ContainerRunner, len(images))
+	for i, image := range images {
+		runners[i] = NewContainerRunner().WithImage(image)
+	}
+	return pullImages(ctx, runners)
+}
+
+// pullImages pulls the images of runners concurrently
+func pullImages(ctx context.Context, runners []*ContainerRunner) error {
+	errs := make([]error, len(runners))
+	var wg sync.WaitGroup
+	for i, r := range runners {
+		wg.Add(1)
+		go func(i int, r *ContainerRunner) {
+			defer wg.Done()
+			if err := r.PullImage(ctx); err != nil {
+				errs[i] = fmt.Errorf("pulling %v: %w", r.image, err)
+			}
+		}(i, r)
+	}
+	wg.Wait()
+
+	return newMultiError(errs)
+}
+
+// ensureImage makes the image available to the daemon before the container
+// is created
+func (e *ContainerRunner) ensureImage(ctx context.Context) error {
+	e.imagePulled = false
+	if e.build != nil {
+		return e.buildImage(ctx)
+	}
+	if len(e.imageTarball) > 0 {
+		return e.loadImage(ctx)
+	}
+
+	if e.pullPolicy != PullAlways {
+		present, err := e.imagePresent(ctx)
+		if err != nil {
+			return err
+		}
+		if present {
+			e.logger.Infoln("image already present")
+			return nil
+		}
+		if e.pullPolicy == PullNever {
+			return &ImageNotFoundError{
+				Reference: e.image,
+				Err:       fmt.Errorf("image is not present and pull policy is %v", e.pullPolicy),
+			}
+		}
+	}
+
+	auth, err := e.registryAuth()
+	if err != nil {
+		return err
+	}
+	e.logger.Infoln("pulling image")
+	progress, err := e.client.ImagePull(ctx, e.image, types.ImagePullOptions{RegistryAuth: auth})
+	if err != nil {
+		return fmt.Errorf("pulling image: %w", classifyImageError(e.image, err))
+	}
+	detector := &platformDetector{}
+	err = e.displayProgress(ctx, struct {
+		io.Reader
+		io.Closer
+	}{io.TeeReader(progress, detector), progress})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("pulling image: %w", classifyImageError(e.image, err))
+	}
+	e.imagePulled = true
+	if detector.found {
+		return e.platformMismatch(fmt.Sprintf("the platform of image %v %v", e.image, platformMismatch))
+	}
+	return nil
+}
+
+// imagePresent returns whether the daemon already has the image
+func (e *ContainerRunner) imagePresent(ctx context.Context) (bool, error) {
+	_, _, err := e.client.ImageInspectWithRaw(ctx, e.image)
+	if client.IsErrImageNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("inspecting image: %w", err)
+	}
+	return true, nil
+}
+
+// loadImage loads the image tarball and sets the runner's image to the
+// reference that was loaded
+func (e *ContainerRunner) loadImage(ctx context.Context) error {
+	e.logger.Infoln("loading image")
+	f, err := os.Open(e.imageTarball)
+	if err != nil {
+		return fmt.Errorf("opening image tarball: %w", err)
+	}
+	defer f.Close()
+
+	resp, err := e.client.ImageLoad(ctx, f, true)
+	if err != nil {
+		return fmt.Errorf("loading image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	image, err := e.loadedImage(resp.Body)
+	if err != nil {
+		return fmt.Errorf("loading image: %w", err)
+	}
+	e.image = image
+	e.logger.Infof("loaded image %v", image)
+	return nil
+}
+
+// loadedImage reads the JSON messages of an image load and returns the
+// reference of the loaded image, preferring a tag over a bare image id
+func (e *ContainerRunner) loadedImage(stream io.Reader) (string, error) {
+	var tag, id string
+	decoder := json.NewDecoder(stream)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		if msg.Error != nil {
+			return "", msg.Error
+		}
+		fmt.Fprint(e.output, msg.Stream)
+
+		line := strings.TrimSpace(msg.Stream)
+		switch {
+		case strings.HasPrefix(line, "Loaded image ID: "):
+			id = strings.TrimPrefix(line, "Loaded image ID: ")
+		case strings.HasPrefix(line, "Loaded image: ") && len(tag) == 0:
+			tag = strings.TrimPrefix(line, "Loaded image: ")
+		}
+	}
+	if len(tag) > 0 {
+		return tag, nil
+	}
+	if len(id) > 0 {
+		return id, nil
+	}
+	return "", errors.New("no image found in tarball")
+}
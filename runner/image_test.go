@@ -0,0 +1,126 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPullCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	runner := NewContainerRunner().WithImage("mongo")
+	runner.client = &mockClient{
+		imagePull: func(ref string) (io.ReadCloser, error) {
+			// A pull that never finishes
+			r, w := io.Pipe()
+			go func() {
+				w.Write([]byte(`{"status":"Downloading"}`))
+				<-time.After(time.Minute)
+				w.Close()
+			}()
+			return r, nil
+		},
+	}
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+	started := time.Now()
+	err := runner.Start(ctx)
+	require.Equal(t, context.Canceled, err)
+	require.True(t, time.Since(started) < 10*time.Second)
+}
+
+func TestPullImages(t *testing.T) {
+	var pulled []string
+	var mu sync.Mutex
+	client := &mockClient{
+		imagePull: func(ref string) (io.ReadCloser, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			pulled = append(pulled, ref)
+			if ref == "docker.io/library/missing" {
+				return nil, errors.New("repository does not exist")
+			}
+			return ioutil.NopCloser(strings.NewReader("")), nil
+		},
+	}
+	var runners []*ContainerRunner
+	for _, image := range []string{"mongo", "redis", "missing"} {
+		r := NewContainerRunner().WithImage(image)
+		r.client = client
+		runners = append(runners, r)
+	}
+
+	err := pullImages(context.Background(), runners)
+	require.ElementsMatch(t, []string{"docker.io/library/mongo", "docker.io/library/redis", "docker.io/library/missing"}, pulled)
+	require.True(t, errors.Is(err, ErrImageNotFound))
+	require.True(t, runners[0].ImageWasPulled())
+	require.Len(t, err.(MultiError), 1)
+}
+
+func TestLoadImage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "image")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tarball := filepath.Join(dir, "image.tar")
+	require.NoError(t, ioutil.WriteFile(tarball, []byte("tarball"), 0644))
+
+	for _, tc := range []struct {
+		name   string
+		stream string
+		image  string
+		err    string
+	}{
+		{
+			name:   "tag preferred over id",
+			stream: `{"stream":"Loaded image ID: sha256:abc\n"}{"stream":"Loaded image: app:1.0\n"}{"stream":"Loaded image: app:latest\n"}`,
+			image:  "app:1.0",
+		},
+		{
+			name:   "bare id",
+			stream: `{"stream":"Loaded image ID: sha256:abc\n"}`,
+			image:  "sha256:abc",
+		},
+		{
+			name:   "error in stream",
+			stream: `{"stream":"Loading layer\n"}{"errorDetail":{"message":"invalid tar header"},"error":"invalid tar header"}`,
+			err:    "invalid tar header",
+		},
+		{
+			name:   "not JSON",
+			stream: "open /var/lib/docker/tmp: no space left on device",
+			err:    "invalid character",
+		},
+		{
+			name: "no image",
+			err:  "no image found in tarball",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := NewContainerRunner().WithImageTarball(tarball)
+			runner.client = &mockClient{
+				imageLoad: func(input io.Reader) (io.ReadCloser, error) {
+					b, err := ioutil.ReadAll(input)
+					require.NoError(t, err)
+					require.Equal(t, "tarball", string(b))
+					return ioutil.NopCloser(strings.NewReader(tc.stream)), nil
+				},
+			}
+			err := runner.loadImage(context.Background())
+			if len(tc.err) > 0 {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.image, runner.Image())
+		})
+	}
+}
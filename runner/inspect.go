@@ -0,0 +1,113 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RestartCount returns how many times Docker restarted the container under
+// its restart policy, see WithRestartPolicy
+func (e *ContainerRunner) RestartCount(ctx context.Context) (int, error) {
+	// If we don't have a container id
+	if len(e.id) == 0 {
+		return 0, ErrNoContainerId
+	}
+
+	info, err := e.client.ContainerInspect(ctx, e.id)
+	if err != nil {
+		return 0, fmt.Errorf("inspecting container: %w", err)
+	}
+	return info.RestartCount, nil
+}
+
+// ContainerState is the state of the container as reported by Docker
+type ContainerState struct {
+	// Status is e.g. "created", "running", "paused", "restarting", "exited"
+	// or "dead"
+	Status     string
+	Running    bool
+	Paused     bool
+	Restarting bool
+	// OOMKilled is true if the kernel killed the container because it ran
+	// out of memory
+	OOMKilled bool
+	ExitCode  int
+	// Error is the error Docker reported when running the container failed
+	Error string
+	// StartedAt and FinishedAt are zero if the container was never started
+	// or never finished
+	StartedAt    time.Time
+	FinishedAt   time.Time
+	RestartCount int
+	// Labels are the labels of the container, including the ones inherited
+	// from its image
+	Labels map[string]string
+}
+
+// InspectState returns the current state of the container from a single
+// inspection, for assertions that would otherwise need Docker's types
+func (e *ContainerRunner) InspectState(ctx context.Context) (*ContainerState, error) {
+	// If we don't have a container id
+	if len(e.id) == 0 {
+		return nil, ErrNoContainerId
+	}
+
+	info, err := e.client.ContainerInspect(ctx, e.id)
+	if err != nil {
+		return nil, fmt.Errorf("inspecting container: %w", err)
+	}
+	state := &ContainerState{}
+	if info.Config != nil {
+		state.Labels = info.Config.Labels
+	}
+	if info.ContainerJSONBase == nil {
+		return state, nil
+	}
+	state.RestartCount = info.RestartCount
+	if info.State == nil {
+		return state, nil
+	}
+	state.Status = info.State.Status
+	state.Running = info.State.Running
+	state.Paused = info.State.Paused
+	state.Restarting = info.State.Restarting
+	state.OOMKilled = info.State.OOMKilled
+	state.ExitCode = info.State.ExitCode
+	state.Error = info.State.Error
+	if state.StartedAt, err = parseStateTime(info.State.StartedAt); err != nil {
+		return nil, fmt.Errorf("parsing start time: %w", err)
+	}
+	if state.FinishedAt, err = parseStateTime(info.State.FinishedAt); err != nil {
+		return nil, fmt.Errorf("parsing finish time: %w", err)
+	}
+	return state, nil
+}
+
+// parseStateTime parses a timestamp of the container state, which Docker
+// reports as the zero time if the event did not happen
+func parseStateTime(s string) (time.Time, error) {
+	if len(s) == 0 {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if t.IsZero() {
+		return time.Time{}, nil
+	}
+	return t, nil
+}
+
+// inspectLabels returns the labels of the container as reported by Docker
+func (e *ContainerRunner) inspectLabels(ctx context.Context) (map[string]string, error) {
+	info, err := e.client.ContainerInspect(ctx, e.id)
+	if err != nil {
+		return nil, fmt.Errorf("inspecting container: %w", err)
+	}
+	if info.Config == nil {
+		return nil, nil
+	}
+	return info.Config.Labels, nil
+}
@@ -0,0 +1,45 @@
+package runner
+
+import (
+	"context"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestInspectState(t *testing.T) {
+	runner := NewContainerRunner()
+	runner.id = "id"
+	runner.client = &mockClient{
+		containerInspect: func(id string) (types.ContainerJSON, error) {
+			return types.ContainerJSON{
+				ContainerJSONBase: &types.ContainerJSONBase{
+					RestartCount: 2,
+					State: &types.ContainerState{
+						Status:     "exited",
+						OOMKilled:  true,
+						ExitCode:   137,
+						StartedAt:  "2020-06-01T12:00:00.5Z",
+						FinishedAt: "0001-01-01T00:00:00Z",
+					},
+				},
+				Config: &container.Config{
+					Labels: map[string]string{LabelRunID: RunID},
+				},
+			}, nil
+		},
+	}
+
+	state, err := runner.InspectState(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, &ContainerState{
+		Status:       "exited",
+		OOMKilled:    true,
+		ExitCode:     137,
+		StartedAt:    time.Date(2020, 6, 1, 12, 0, 0, 5e8, time.UTC),
+		RestartCount: 2,
+		Labels:       map[string]string{LabelRunID: RunID},
+	}, state)
+}
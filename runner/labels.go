@@ -0,0 +1,112 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	// LabelManaged marks every container created by this package, unless
+	// ContainerRunnerOpts.DisableAutoLabels is set. Its value is "true".
+	LabelManaged = "com.clarkmcc.container/managed"
+	// LabelRunID records the RunID of the process that created the
+	// container
+	LabelRunID = "com.clarkmcc.container/run"
+)
+
+var (
+	// RunID identifies the current process in the LabelRunID label of the
+	// containers it creates
+	RunID = uuid.New().String()
+)
+
+// WithLabel sets a label on the container
+func (r *ContainerRunner) WithLabel(key, val string) *ContainerRunner {
+	if len(key) == 0 {
+		r.setErr(fmt.Errorf("label key must not be empty"))
+		return r
+	}
+	r.labels[key] = val
+	return r
+}
+
+// containerLabels returns the labels of the container, including the
+// automatic ones
+func (e *ContainerRunner) containerLabels() map[string]string {
+	labels := copyStringMap(e.labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	if !e.opts.DisableAutoLabels {
+		labels[LabelManaged] = "true"
+		labels[LabelRunID] = RunID
+	}
+	return labels
+}
+
+// PruneOrphans force removes containers, running or not, that were created
+// by this package in other processes more than olderThan ago, which typically
+// happens when a test binary is killed before it can stop its containers.
+// Containers are found by their LabelManaged label, or by the given
+// "key=value" or "key" labels instead if any are passed. Containers created by
+// the current process are never removed.
+//
+// PruneOrphans can't tell an orphan from a container that another process is
+// still using. In particular go test runs the test binaries of several
+// packages in parallel, so calling it from TestMain would kill the containers
+// of the other packages if it weren't for olderThan; choose a value longer
+// than any test run. A zero olderThan removes the containers regardless of
+// their age. The ids of the removed containers are returned.
+func PruneOrphans(ctx context.Context, olderThan time.Duration, labels ...string) ([]string, error) {
+	c, err := client.NewEnvClient()
+	if err != nil {
+		return nil, fmt.Errorf("creating env client: %w", err)
+	}
+	defer c.Close()
+	return pruneOrphans(ctx, c, olderThan, labels)
+}
+
+// pruneOrphans implements PruneOrphans with the given client
+func pruneOrphans(ctx context.Context, c client.CommonAPIClient, olderThan time.Duration, labels []string) ([]string, error) {
+	if len(labels) == 0 {
+		labels = []string{LabelManaged + "=true"}
+	}
+	cutoff := time.Now().Add(-olderThan)
+	return removeLabeled(ctx, c, labels, func(container types.Container) bool {
+		return container.Labels[LabelRunID] == RunID || time.Unix(container.Created, 0).After(cutoff)
+	})
+}
+
+// removeLabeled force removes the containers that have all the labels,
+// skipping the ones for which keep, if not nil, returns true
+func removeLabeled(ctx context.Context, c client.CommonAPIClient, labels []string, keep func(types.Container) bool) ([]string, error) {
+	args := filters.NewArgs()
+	for _, label := range labels {
+		args.Add("label", label)
+	}
+	containers, err := c.ContainerList(ctx, types.ContainerListOptions{All: true, Filters: args})
+	if err != nil {
+		return nil, fmt.Errorf("listing containers: %w", err)
+	}
+
+	var removed []string
+	for _, container := range containers {
+		if keep != nil && keep(container) {
+			continue
+		}
+		err := c.ContainerRemove(ctx, container.ID, types.ContainerRemoveOptions{
+			RemoveVolumes: true,
+			Force:         true,
+		})
+		if err != nil && !client.IsErrNotFound(err) {
+			return removed, fmt.Errorf("removing container %v: %w", container.ID, err)
+		}
+		removed = append(removed, container.ID)
+	}
+	return removed, nil
+}
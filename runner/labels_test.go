@@ -0,0 +1,45 @@
+package runner
+
+import (
+	"context"
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestContainerLabels(t *testing.T) {
+	runner := NewContainerRunner().WithLabel("team", "storage")
+	require.Equal(t, map[string]string{
+		"team":       "storage",
+		LabelManaged: "true",
+		LabelRunID:   RunID,
+	}, runner.containerConfig().Labels)
+
+	runner.opts.DisableAutoLabels = true
+	require.Equal(t, map[string]string{"team": "storage"}, runner.containerConfig().Labels)
+}
+
+func TestPruneOrphans(t *testing.T) {
+	var removed []string
+	c := &mockClient{
+		containerList: func(options types.ContainerListOptions) ([]types.Container, error) {
+			require.True(t, options.Filters.ExactMatch("label", LabelManaged+"=true"))
+			return []types.Container{
+				{ID: "orphan", Created: time.Now().Add(-2 * time.Hour).Unix(), Labels: map[string]string{LabelManaged: "true", LabelRunID: "previous"}},
+				// Possibly still used by a test binary running in parallel
+				{ID: "recent", Created: time.Now().Add(-time.Minute).Unix(), Labels: map[string]string{LabelManaged: "true", LabelRunID: "parallel"}},
+				{ID: "current", Created: time.Now().Add(-2 * time.Hour).Unix(), Labels: map[string]string{LabelManaged: "true", LabelRunID: RunID}},
+			}, nil
+		},
+		containerRemove: func(id string, options types.ContainerRemoveOptions) error {
+			removed = append(removed, id)
+			return nil
+		},
+	}
+
+	ids, err := pruneOrphans(context.Background(), c, time.Hour, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"orphan"}, ids)
+	require.Equal(t, []string{"orphan"}, removed)
+}
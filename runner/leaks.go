@@ -0,0 +1,55 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrContainersLeaked is returned by LeakCheck if containers were left running
+var ErrContainersLeaked = errors.New("containers were left running")
+
+// leaks tracks the containers started by runners with
+// ContainerRunnerOpts.DetectLeaks that were not stopped yet, keyed by id
+var leaks = struct {
+	sync.Mutex
+	containers map[string]string
+}{containers: map[string]string{}}
+
+// LeakCheck returns ErrContainersLeaked, listing the names and ids of the
+// containers, if any container started by a runner with
+// ContainerRunnerOpts.DetectLeaks enabled was not stopped. It is meant to be
+// called when the process exits, e.g. at the end of TestMain, to catch tests
+// that forgot to call Stop.
+func LeakCheck() error {
+	leaks.Lock()
+	defer leaks.Unlock()
+	if len(leaks.containers) == 0 {
+		return nil
+	}
+	leaked := make([]string, 0, len(leaks.containers))
+	for id, name := range leaks.containers {
+		leaked = append(leaked, fmt.Sprintf("%v (%v)", name, id))
+	}
+	sort.Strings(leaked)
+	return fmt.Errorf("%w: %v", ErrContainersLeaked, strings.Join(leaked, ", "))
+}
+
+// trackLeak records the started container until untrackLeak is called
+func (e *ContainerRunner) trackLeak() {
+	if !e.opts.DetectLeaks {
+		return
+	}
+	leaks.Lock()
+	defer leaks.Unlock()
+	leaks.containers[e.id] = e.displayName()
+}
+
+// untrackLeak forgets the container once it was stopped
+func (e *ContainerRunner) untrackLeak() {
+	leaks.Lock()
+	defer leaks.Unlock()
+	delete(leaks.containers, e.id)
+}
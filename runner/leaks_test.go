@@ -0,0 +1,38 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestLeakCheck(t *testing.T) {
+	client := &mockClient{
+		containerCreate: func(name string) (container.ContainerCreateCreatedBody, error) {
+			return container.ContainerCreateCreatedBody{ID: name + "-id"}, nil
+		},
+	}
+	start := func(name string) *ContainerRunner {
+		r := NewContainerRunner().
+			WithImage("redis").
+			WithName(name).
+			WithOptions(&ContainerRunnerOpts{RemoveOnFinalization: true, DetectLeaks: true})
+		r.client = client
+		require.NoError(t, r.Start(context.Background()))
+		return r
+	}
+
+	stopped := start("stopped")
+	leaked := start("leaked")
+	require.NoError(t, stopped.Stop(context.Background()))
+
+	err := LeakCheck()
+	require.True(t, errors.Is(err, ErrContainersLeaked))
+	require.Contains(t, err.Error(), "leaked (leaked-id)")
+	require.NotContains(t, err.Error(), "stopped")
+
+	require.NoError(t, leaked.Stop(context.Background()))
+	require.NoError(t, LeakCheck())
+}
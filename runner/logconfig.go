@@ -0,0 +1,59 @@
+package runner
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// logSizePattern matches the sizes understood by the json-file log driver,
+// e.g. "10m"
+var logSizePattern = regexp.MustCompile(`^[0-9]+[kmg]?$`)
+
+// WithLogDriver sets the log driver of the container, e.g. "json-file" or
+// "none", and its options. Note that the runner's log functions, such as
+// WaitForLogMatch, only work with drivers that Docker can read back.
+func (r *ContainerRunner) WithLogDriver(driver string, opts map[string]string) *ContainerRunner {
+	r.logConfig.Type = driver
+	if r.logConfig.Config == nil {
+		r.logConfig.Config = map[string]string{}
+	}
+	for k, v := range opts {
+		r.logConfig.Config[k] = v
+	}
+	return r
+}
+
+// WithMaxLogSize rotates the container's log once it reaches size, e.g.
+// "10m", so that long-running containers don't fill the disk. It uses the
+// json-file log driver unless another rotating driver was set.
+func (r *ContainerRunner) WithMaxLogSize(size string) *ContainerRunner {
+	if !logSizePattern.MatchString(size) {
+		r.setErr(fmt.Errorf("invalid max log size %q", size))
+		return r
+	}
+	return r.withLogRotation("max-size", size)
+}
+
+// WithMaxLogFiles keeps at most n rotated log files of the container. It only
+// has an effect together with WithMaxLogSize.
+func (r *ContainerRunner) WithMaxLogFiles(n int) *ContainerRunner {
+	if n < 1 {
+		r.setErr(fmt.Errorf("max log files %v must be at least 1", n))
+		return r
+	}
+	return r.withLogRotation("max-file", strconv.Itoa(n))
+}
+
+// withLogRotation sets a rotation option of the json-file or local log driver
+func (r *ContainerRunner) withLogRotation(key, val string) *ContainerRunner {
+	switch r.logConfig.Type {
+	case "":
+		r.logConfig.Type = "json-file"
+	case "json-file", "local":
+	default:
+		r.setErr(fmt.Errorf("log driver %v does not support %v", r.logConfig.Type, key))
+		return r
+	}
+	return r.WithLogDriver(r.logConfig.Type, map[string]string{key: val})
+}
@@ -0,0 +1,25 @@
+package runner
+
+import (
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestWithMaxLogSize(t *testing.T) {
+	runner := NewContainerRunner().
+		WithImage("nginx").
+		WithMaxLogSize("10m").
+		WithMaxLogFiles(3)
+	require.NoError(t, runner.err)
+	require.Equal(t, container.LogConfig{
+		Type:   "json-file",
+		Config: map[string]string{"max-size": "10m", "max-file": "3"},
+	}, runner.hostConfig().LogConfig)
+
+	runner = NewContainerRunner().WithMaxLogSize("ten megabytes")
+	require.Error(t, runner.err)
+
+	runner = NewContainerRunner().WithLogDriver("syslog", nil).WithMaxLogFiles(3)
+	require.Error(t, runner.err)
+}
@@ -0,0 +1,192 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// LogsOption restricts the logs returned by Logs and StreamLogs
+type LogsOption func(*logsConfig)
+
+// logsConfig is the selection of logs of a single call to Logs or StreamLogs
+type logsConfig struct {
+	since      time.Time
+	tail       int
+	timestamps bool
+}
+
+// LogsSince only returns the logs written at or after t, e.g. since the test
+// started
+func LogsSince(t time.Time) LogsOption {
+	return func(c *logsConfig) {
+		c.since = t
+	}
+}
+
+// LogsTail only returns the last n lines of the logs written so far. A
+// negative n returns every line, which is the default.
+func LogsTail(n int) LogsOption {
+	return func(c *logsConfig) {
+		c.tail = n
+	}
+}
+
+// LogsTimestamps prefixes every line with the time Docker received it, in
+// RFC3339Nano format. Timestamps are omitted by default.
+func LogsTimestamps(timestamps bool) LogsOption {
+	return func(c *logsConfig) {
+		c.timestamps = timestamps
+	}
+}
+
+// containerLogsOptions returns the Docker options that select the logs
+func (c logsConfig) containerLogsOptions(follow bool) types.ContainerLogsOptions {
+	options := types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     follow,
+		Timestamps: c.timestamps,
+	}
+	if !c.since.IsZero() {
+		options.Since = fmt.Sprintf("%d.%09d", c.since.Unix(), c.since.Nanosecond())
+	}
+	if c.tail >= 0 {
+		options.Tail = strconv.Itoa(c.tail)
+	}
+	return options
+}
+
+// newLogsConfig applies opts to the default selection of every line
+func newLogsConfig(opts []LogsOption) logsConfig {
+	c := logsConfig{tail: -1}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// Logs returns the stdout and stderr the container wrote so far, interleaved
+// in the order they were written
+func (e *ContainerRunner) Logs(ctx context.Context, opts ...LogsOption) (string, error) {
+	// If we don't have a container id
+	if len(e.id) == 0 {
+		return "", ErrNoContainerId
+	}
+
+	logs, err := e.client.ContainerLogs(ctx, e.id, newLogsConfig(opts).containerLogsOptions(false))
+	if err != nil {
+		return "", fmt.Errorf("getting logs: %w", err)
+	}
+	defer logs.Close()
+
+	var b strings.Builder
+	if _, err := stdcopy.StdCopy(&b, &b, logs); err != nil {
+		return "", fmt.Errorf("reading logs: %w", err)
+	}
+	return b.String(), nil
+}
+
+// StreamLogs follows the container's logs and copies its stdout and stderr to
+// the given writers until the container stops, in which case it returns nil,
+// or until ctx is done.
+func (e *ContainerRunner) StreamLogs(ctx context.Context, stdout, stderr io.Writer, opts ...LogsOption) error {
+	// If we don't have a container id
+	if len(e.id) == 0 {
+		return ErrNoContainerId
+	}
+
+	logs, err := e.client.ContainerLogs(ctx, e.id, newLogsConfig(opts).containerLogsOptions(true))
+	if err != nil {
+		return fmt.Errorf("following logs: %w", err)
+	}
+	defer logs.Close()
+
+	if _, err := stdcopy.StdCopy(stdout, stderr, logs); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("reading logs: %w", err)
+	}
+	return nil
+}
+
+// WaitForLogMatch follows the container's stdout and stderr and returns the
+// first line matching pattern, for at most timeout. This is useful to capture
+// values that images print at startup, such as generated credentials or URLs.
+func (e *ContainerRunner) WaitForLogMatch(ctx context.Context, pattern *regexp.Regexp, timeout time.Duration) (string, error) {
+	var match string
+	err := e.scanLogs(ctx, timeout, func(line string) bool {
+		if pattern.MatchString(line) {
+			match = line
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		return "", fmt.Errorf("waiting for log matching %v: %w", pattern, err)
+	}
+	return match, nil
+}
+
+// scanLogs follows the container's logs and calls fn with every line until fn
+// returns true. It fails with ErrWaitTimeout if timeout elapses first, and
+// with ErrLogsEnded if the container exits first.
+func (e *ContainerRunner) scanLogs(ctx context.Context, timeout time.Duration, fn func(line string) bool) error {
+	// If we don't have a container id
+	if len(e.id) == 0 {
+		return ErrNoContainerId
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	logs, err := e.client.ContainerLogs(ctx, e.id, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+	})
+	if err != nil {
+		return timeoutErr(ctx, fmt.Errorf("following logs: %w", err))
+	}
+	defer logs.Close()
+
+	r, w := io.Pipe()
+	go func() {
+		_, err := stdcopy.StdCopy(w, w, logs)
+		w.CloseWithError(err)
+	}()
+	defer r.Close()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		atomic.AddInt32(&e.attempts, 1)
+		if fn(scanner.Text()) {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return timeoutErr(ctx, fmt.Errorf("reading logs: %w", err))
+	}
+	return timeoutErr(ctx, ErrLogsEnded)
+}
+
+// timeoutErr returns ErrWaitTimeout if ctx expired and err otherwise
+func timeoutErr(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ErrWaitTimeout
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
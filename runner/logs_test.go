@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestLogs(t *testing.T) {
+	var options types.ContainerLogsOptions
+	runner := NewContainerRunner().WithImage("mongo")
+	runner.client = &mockClient{
+		containerLogs: func(id string, o types.ContainerLogsOptions) (io.ReadCloser, error) {
+			options = o
+			var b bytes.Buffer
+			stdcopy.NewStdWriter(&b, stdcopy.Stdout).Write([]byte("waiting for connections\n"))
+			stdcopy.NewStdWriter(&b, stdcopy.Stderr).Write([]byte("deprecated option\n"))
+			return ioutil.NopCloser(&b), nil
+		},
+	}
+	_, err := runner.Logs(context.Background())
+	require.Equal(t, ErrNoContainerId, err)
+	require.NoError(t, runner.Start(context.Background()))
+
+	logs, err := runner.Logs(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "waiting for connections\ndeprecated option\n", logs)
+	require.Equal(t, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true}, options)
+
+	since := time.Unix(1591012800, 5e8)
+	_, err = runner.Logs(context.Background(), LogsSince(since), LogsTail(100), LogsTimestamps(true))
+	require.NoError(t, err)
+	require.Equal(t, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Since:      "1591012800.500000000",
+		Tail:       "100",
+		Timestamps: true,
+	}, options)
+
+	var stdout, stderr bytes.Buffer
+	require.NoError(t, runner.StreamLogs(context.Background(), &stdout, &stderr, LogsTail(0)))
+	require.Equal(t, "waiting for connections\n", stdout.String())
+	require.Equal(t, "deprecated option\n", stderr.String())
+	require.True(t, options.Follow)
+	require.Equal(t, "0", options.Tail)
+}
+
+func TestWaitForLogMatch(t *testing.T) {
+	// logs streams lines, then keeps the stream open for open
+	logs := func(open time.Duration, lines ...string) func(string, types.ContainerLogsOptions) (io.ReadCloser, error) {
+		return func(id string, o types.ContainerLogsOptions) (io.ReadCloser, error) {
+			require.True(t, o.Follow)
+			r, w := io.Pipe()
+			go func() {
+				for _, line := range lines {
+					stdcopy.NewStdWriter(w, stdcopy.Stderr).Write([]byte(line + "\n"))
+				}
+				// Until the daemon ends the stream, like after ctx expired
+				time.Sleep(open)
+				w.Close()
+			}()
+			return r, nil
+		}
+	}
+	pattern := regexp.MustCompile(`password: (\w+)`)
+	runner := NewContainerRunner()
+	_, err := runner.WaitForLogMatch(context.Background(), pattern, time.Second)
+	require.True(t, errors.Is(err, ErrNoContainerId))
+	runner.id = "id"
+
+	runner.client = &mockClient{containerLogs: logs(time.Minute, "starting", "generated password: s3cr3t", "ready")}
+	line, err := runner.WaitForLogMatch(context.Background(), pattern, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "generated password: s3cr3t", line)
+
+	runner.client = &mockClient{containerLogs: logs(0, "starting", "exiting")}
+	_, err = runner.WaitForLogMatch(context.Background(), pattern, time.Second)
+	require.True(t, errors.Is(err, ErrLogsEnded))
+
+	runner.client = &mockClient{containerLogs: logs(200*time.Millisecond, "starting")}
+	_, err = runner.WaitForLogMatch(context.Background(), pattern, 50*time.Millisecond)
+	require.True(t, errors.Is(err, ErrWaitTimeout))
+}
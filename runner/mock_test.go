@@ -0,0 +1,257 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mockClient is a docker client whose methods can be replaced per test.
+// Methods that were not replaced succeed, methods that are not implemented
+// by the mock panic.
+type mockClient struct {
+	client.CommonAPIClient
+	imagePull        func(ref string) (io.ReadCloser, error)
+	containerCreate  func(name string) (container.ContainerCreateCreatedBody, error)
+	containerStart   func(id string) error
+	containerStop    func(ctx context.Context, id string) error
+	containerKill    func(id, signal string) error
+	containerCommit  func(id string, options types.ContainerCommitOptions) (types.IDResponse, error)
+	containerExport  func(id string) (io.ReadCloser, error)
+	containerUpdate  func(id string, config container.UpdateConfig) (container.ContainerUpdateOKBody, error)
+	containerRemove  func(id string, options types.ContainerRemoveOptions) error
+	containerInspect func(id string) (types.ContainerJSON, error)
+	containerList    func(options types.ContainerListOptions) ([]types.Container, error)
+	copyFrom         func(path string) (io.ReadCloser, types.ContainerPathStat, error)
+	containerWait    func(id string) (int64, error)
+	containerLogs    func(id string, options types.ContainerLogsOptions) (io.ReadCloser, error)
+	containerStats   func(ctx context.Context, stream bool) (io.ReadCloser, error)
+	networkCreate    func(name string, options types.NetworkCreate) (types.NetworkCreateResponse, error)
+	networkInspect   func(name string) (types.NetworkResource, error)
+	networkList      func(options types.NetworkListOptions) ([]types.NetworkResource, error)
+	networkRemove    func(id string) error
+	networkConnect   func(name, id string, config *network.EndpointSettings) error
+	containerAttach  func(options types.ContainerAttachOptions) (types.HijackedResponse, error)
+	execAttach       func(config types.ExecConfig) (types.HijackedResponse, error)
+	execInspect      func(id string) (types.ContainerExecInspect, error)
+	imageInspect     func(image string) (types.ImageInspect, error)
+	info             func() (types.Info, error)
+	volumeCreate     func(options volume.VolumesCreateBody) (types.Volume, error)
+	volumeRemove     func(name string, force bool) error
+	events           func(options types.EventsOptions) (<-chan events.Message, <-chan error)
+	imageLoad        func(input io.Reader) (io.ReadCloser, error)
+	imageBuild       func(buildContext io.Reader, options types.ImageBuildOptions) (types.ImageBuildResponse, error)
+}
+
+func (m *mockClient) Ping(ctx context.Context) (types.Ping, error) {
+	return types.Ping{}, nil
+}
+
+func (m *mockClient) ImagePull(ctx context.Context, ref string, options types.ImagePullOptions) (io.ReadCloser, error) {
+	if m.imagePull == nil {
+		return ioutil.NopCloser(strings.NewReader("")), nil
+	}
+	return m.imagePull(ref)
+}
+
+func (m *mockClient) ContainerCreate(ctx context.Context, config *container.Config, hostConfig *container.HostConfig, networkingConfig *network.NetworkingConfig, name string) (container.ContainerCreateCreatedBody, error) {
+	if m.containerCreate == nil {
+		return container.ContainerCreateCreatedBody{ID: "id"}, nil
+	}
+	return m.containerCreate(name)
+}
+
+func (m *mockClient) ContainerStart(ctx context.Context, id string, options types.ContainerStartOptions) error {
+	if m.containerStart == nil {
+		return nil
+	}
+	return m.containerStart(id)
+}
+
+func (m *mockClient) ContainerStop(ctx context.Context, id string, timeout *time.Duration) error {
+	if m.containerStop == nil {
+		return nil
+	}
+	return m.containerStop(ctx, id)
+}
+
+func (m *mockClient) ContainerKill(ctx context.Context, id, signal string) error {
+	if m.containerKill == nil {
+		return nil
+	}
+	return m.containerKill(id, signal)
+}
+
+func (m *mockClient) ContainerUpdate(ctx context.Context, id string, config container.UpdateConfig) (container.ContainerUpdateOKBody, error) {
+	return m.containerUpdate(id, config)
+}
+
+func (m *mockClient) ContainerCommit(ctx context.Context, id string, options types.ContainerCommitOptions) (types.IDResponse, error) {
+	return m.containerCommit(id, options)
+}
+
+func (m *mockClient) ContainerExport(ctx context.Context, id string) (io.ReadCloser, error) {
+	return m.containerExport(id)
+}
+
+func (m *mockClient) ContainerRemove(ctx context.Context, id string, options types.ContainerRemoveOptions) error {
+	if m.containerRemove == nil {
+		return nil
+	}
+	return m.containerRemove(id, options)
+}
+
+func (m *mockClient) ContainerInspect(ctx context.Context, id string) (types.ContainerJSON, error) {
+	if m.containerInspect == nil {
+		return runningContainer(), nil
+	}
+	return m.containerInspect(id)
+}
+
+// notFoundError is recognized by client.IsErrNotFound
+type notFoundError struct{}
+
+func (notFoundError) Error() string  { return "no such container" }
+func (notFoundError) NotFound() bool { return true }
+
+// runningContainer is the inspect result of a running container
+func runningContainer() types.ContainerJSON {
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			State: &types.ContainerState{Running: true},
+		},
+	}
+}
+
+func (m *mockClient) ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error) {
+	return m.containerList(options)
+}
+
+func (m *mockClient) CopyFromContainer(ctx context.Context, id, path string) (io.ReadCloser, types.ContainerPathStat, error) {
+	return m.copyFrom(path)
+}
+
+func (m *mockClient) ImageInspectWithRaw(ctx context.Context, image string) (types.ImageInspect, []byte, error) {
+	if m.imageInspect == nil {
+		return types.ImageInspect{ID: image}, nil, nil
+	}
+	info, err := m.imageInspect(image)
+	return info, nil, err
+}
+
+func (m *mockClient) ContainerWait(ctx context.Context, id string) (int64, error) {
+	if m.containerWait == nil {
+		return 0, nil
+	}
+	return m.containerWait(id)
+}
+
+func (m *mockClient) ContainerLogs(ctx context.Context, id string, options types.ContainerLogsOptions) (io.ReadCloser, error) {
+	return m.containerLogs(id, options)
+}
+
+func (m *mockClient) ContainerStats(ctx context.Context, id string, stream bool) (types.ContainerStats, error) {
+	body, err := m.containerStats(ctx, stream)
+	return types.ContainerStats{Body: body}, err
+}
+
+// safeBuffer is a bytes.Buffer that can be written and read concurrently
+type safeBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *safeBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *safeBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (m *mockClient) ImageBuild(ctx context.Context, buildContext io.Reader, options types.ImageBuildOptions) (types.ImageBuildResponse, error) {
+	return m.imageBuild(buildContext, options)
+}
+
+func (m *mockClient) ImageLoad(ctx context.Context, input io.Reader, quiet bool) (types.ImageLoadResponse, error) {
+	body, err := m.imageLoad(input)
+	return types.ImageLoadResponse{Body: body, JSON: true}, err
+}
+
+func (m *mockClient) Events(ctx context.Context, options types.EventsOptions) (<-chan events.Message, <-chan error) {
+	return m.events(options)
+}
+
+func (m *mockClient) NetworkCreate(ctx context.Context, name string, options types.NetworkCreate) (types.NetworkCreateResponse, error) {
+	return m.networkCreate(name, options)
+}
+
+func (m *mockClient) NetworkInspect(ctx context.Context, name string) (types.NetworkResource, error) {
+	return m.networkInspect(name)
+}
+
+func (m *mockClient) NetworkList(ctx context.Context, options types.NetworkListOptions) ([]types.NetworkResource, error) {
+	if m.networkList == nil {
+		return nil, nil
+	}
+	return m.networkList(options)
+}
+
+func (m *mockClient) NetworkRemove(ctx context.Context, id string) error {
+	if m.networkRemove == nil {
+		return nil
+	}
+	return m.networkRemove(id)
+}
+
+func (m *mockClient) NetworkConnect(ctx context.Context, name, id string, config *network.EndpointSettings) error {
+	if m.networkConnect == nil {
+		return nil
+	}
+	return m.networkConnect(name, id, config)
+}
+
+func (m *mockClient) Info(ctx context.Context) (types.Info, error) {
+	if m.info == nil {
+		return types.Info{}, nil
+	}
+	return m.info()
+}
+
+func (m *mockClient) VolumeCreate(ctx context.Context, options volume.VolumesCreateBody) (types.Volume, error) {
+	return m.volumeCreate(options)
+}
+
+func (m *mockClient) VolumeRemove(ctx context.Context, name string, force bool) error {
+	return m.volumeRemove(name, force)
+}
+
+func (m *mockClient) ContainerAttach(ctx context.Context, id string, options types.ContainerAttachOptions) (types.HijackedResponse, error) {
+	return m.containerAttach(options)
+}
+
+func (m *mockClient) ContainerExecCreate(ctx context.Context, id string, config types.ExecConfig) (types.IDResponse, error) {
+	return types.IDResponse{ID: "exec"}, nil
+}
+
+func (m *mockClient) ContainerExecAttach(ctx context.Context, id string, config types.ExecConfig) (types.HijackedResponse, error) {
+	return m.execAttach(config)
+}
+
+func (m *mockClient) ContainerExecInspect(ctx context.Context, id string) (types.ContainerExecInspect, error) {
+	return m.execInspect(id)
+}
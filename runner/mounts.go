@@ -0,0 +1,158 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types/mount"
+	"path/filepath"
+	"strings"
+)
+
+// Consistency is the consistency requirement of a bind mount, which Docker
+// Desktop for Mac uses to trade consistency between the host and the
+// container for file system performance
+type Consistency string
+
+const (
+	// ConsistencyDefault uses Docker's default, which is consistent
+	ConsistencyDefault Consistency = ""
+	// ConsistencyConsistent keeps the host and the container perfectly
+	// consistent
+	ConsistencyConsistent Consistency = "consistent"
+	// ConsistencyCached lets the container's view lag behind the host
+	ConsistencyCached Consistency = "cached"
+	// ConsistencyDelegated lets the host's view lag behind the container
+	ConsistencyDelegated Consistency = "delegated"
+)
+
+// MountOption customizes a mount created by WithVolume or WithTmpfs
+type MountOption func(*mountSpec) error
+
+// mountSpec is a mount being built by WithVolume or WithTmpfs
+type mountSpec struct {
+	mount.Mount
+	consistency Consistency
+}
+
+// MountReadOnly mounts the volume or tmpfs read-only
+func MountReadOnly() MountOption {
+	return func(m *mountSpec) error {
+		m.ReadOnly = true
+		return nil
+	}
+}
+
+// MountPropagation sets the propagation mode of a bind mount, e.g.
+// mount.PropagationRShared, which matters when the mounted directory itself
+// contains mounts. Docker's default (rprivate) is used when unspecified.
+func MountPropagation(propagation mount.Propagation) MountOption {
+	return func(m *mountSpec) error {
+		if m.Type != mount.TypeBind {
+			return errors.New("propagation is only supported for bind mounts")
+		}
+		for _, p := range mount.Propagations {
+			if p == propagation {
+				if m.BindOptions == nil {
+					m.BindOptions = &mount.BindOptions{}
+				}
+				m.BindOptions.Propagation = propagation
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid mount propagation %q", propagation)
+	}
+}
+
+// MountConsistency sets the consistency requirement of a bind mount, see
+// Consistency. Daemons that don't support it, such as Docker on Linux,
+// ignore it.
+func MountConsistency(consistency Consistency) MountOption {
+	return func(m *mountSpec) error {
+		if m.Type != mount.TypeBind {
+			return errors.New("consistency is only supported for bind mounts")
+		}
+		switch consistency {
+		case ConsistencyDefault, ConsistencyConsistent, ConsistencyCached, ConsistencyDelegated:
+			m.consistency = consistency
+			return nil
+		}
+		return fmt.Errorf("invalid mount consistency %q", consistency)
+	}
+}
+
+// WithVolume bind mounts the host directory or file at hostPath into the
+// container at containerPath. Relative host paths are resolved against the
+// working directory.
+func (r *ContainerRunner) WithVolume(hostPath, containerPath string, opts ...MountOption) *ContainerRunner {
+	source, err := filepath.Abs(hostPath)
+	if err != nil {
+		r.setErr(fmt.Errorf("resolving volume path %v: %w", hostPath, err))
+		return r
+	}
+	return r.withMount(mount.Mount{
+		Type:   mount.TypeBind,
+		Source: source,
+		Target: containerPath,
+	}, opts)
+}
+
+// WithTmpfs mounts an in-memory tmpfs into the container at containerPath,
+// limited to sizeBytes, or to Docker's default if it is zero. This is useful
+// for fast scratch space that disappears with the container.
+func (r *ContainerRunner) WithTmpfs(containerPath string, sizeBytes int64, opts ...MountOption) *ContainerRunner {
+	if sizeBytes < 0 {
+		r.setErr(fmt.Errorf("mounting %v: tmpfs size %v must not be negative", containerPath, sizeBytes))
+		return r
+	}
+	m := mount.Mount{
+		Type:   mount.TypeTmpfs,
+		Target: containerPath,
+	}
+	if sizeBytes > 0 {
+		m.TmpfsOptions = &mount.TmpfsOptions{SizeBytes: sizeBytes}
+	}
+	return r.withMount(m, opts)
+}
+
+// WithMount adds m to the container's mounts as is, for mount options that
+// WithVolume and WithTmpfs don't cover, such as volume driver options or the
+// mode of a tmpfs
+func (r *ContainerRunner) WithMount(m mount.Mount) *ContainerRunner {
+	r.mounts = append(r.mounts, m)
+	return r
+}
+
+// withMount applies opts to m and adds it to the container's mounts
+func (r *ContainerRunner) withMount(m mount.Mount, opts []MountOption) *ContainerRunner {
+	spec := mountSpec{Mount: m}
+	for _, opt := range opts {
+		if err := opt(&spec); err != nil {
+			r.setErr(fmt.Errorf("mounting %v: %w", m.Target, err))
+			return r
+		}
+	}
+
+	// The mount API has no notion of consistency, which is only understood
+	// in the bind syntax
+	if spec.consistency != ConsistencyDefault {
+		r.binds = append(r.binds, spec.bind())
+		return r
+	}
+	r.mounts = append(r.mounts, spec.Mount)
+	return r
+}
+
+// bind formats the bind mount in the "source:target:options" syntax
+func (m *mountSpec) bind() string {
+	options := []string{"rw"}
+	if m.ReadOnly {
+		options[0] = "ro"
+	}
+	if m.BindOptions != nil && len(m.BindOptions.Propagation) > 0 {
+		options = append(options, string(m.BindOptions.Propagation))
+	}
+	if m.consistency != ConsistencyDefault {
+		options = append(options, string(m.consistency))
+	}
+	return fmt.Sprintf("%v:%v:%v", m.Source, m.Target, strings.Join(options, ","))
+}
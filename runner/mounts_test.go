@@ -0,0 +1,50 @@
+package runner
+
+import (
+	"github.com/docker/docker/api/types/mount"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestWithVolume(t *testing.T) {
+	runner := NewContainerRunner().
+		WithVolume("/data", "/var/lib/data", MountReadOnly(), MountPropagation(mount.PropagationRSlave)).
+		WithVolume("/src", "/app", MountConsistency(ConsistencyCached))
+
+	require.NoError(t, runner.err)
+	require.Equal(t, []mount.Mount{{
+		Type:        mount.TypeBind,
+		Source:      "/data",
+		Target:      "/var/lib/data",
+		ReadOnly:    true,
+		BindOptions: &mount.BindOptions{Propagation: mount.PropagationRSlave},
+	}}, runner.mounts)
+	require.Equal(t, []string{"/src:/app:rw,cached"}, runner.binds)
+
+	runner = NewContainerRunner().WithVolume("/src", "/app", MountConsistency("eventual"))
+	require.Error(t, runner.err)
+}
+
+func TestWithTmpfs(t *testing.T) {
+	volume := mount.Mount{
+		Type:   mount.TypeVolume,
+		Source: "cache",
+		Target: "/cache",
+		VolumeOptions: &mount.VolumeOptions{
+			DriverConfig: &mount.Driver{Name: "local", Options: map[string]string{"type": "nfs"}},
+		},
+	}
+	runner := NewContainerRunner().
+		WithTmpfs("/tmp", 64<<20).
+		WithMount(volume)
+
+	require.NoError(t, runner.err)
+	require.Equal(t, []mount.Mount{{
+		Type:         mount.TypeTmpfs,
+		Target:       "/tmp",
+		TmpfsOptions: &mount.TmpfsOptions{SizeBytes: 64 << 20},
+	}, volume}, runner.hostConfig().Mounts)
+
+	runner = NewContainerRunner().WithTmpfs("/tmp", 0, MountPropagation(mount.PropagationShared))
+	require.Error(t, runner.err)
+}
@@ -0,0 +1,249 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"net"
+	"strings"
+)
+
+// WithNetwork attaches the container to the named networks, which must exist.
+// The container is created on the first network and connected to the others
+// before it is started. Calling WithNetwork again adds more networks.
+func (r *ContainerRunner) WithNetwork(names ...string) *ContainerRunner {
+	for _, name := range names {
+		if len(name) == 0 {
+			r.setErr(fmt.Errorf("network name must not be empty"))
+			return r
+		}
+		if !containsString(r.networks, name) {
+			r.networks = append(r.networks, name)
+		}
+	}
+	return r
+}
+
+// WithNetworkAlias adds DNS aliases under which other containers on the named
+// network can reach the container. The network is attached as if passed to
+// WithNetwork. Calling WithNetworkAlias again for the same network adds more
+// aliases, so each network can have several.
+func (r *ContainerRunner) WithNetworkAlias(name string, aliases ...string) *ContainerRunner {
+	r.WithNetwork(name)
+	for _, alias := range aliases {
+		if !containsString(r.networkAliases[name], alias) {
+			r.networkAliases[name] = append(r.networkAliases[name], alias)
+		}
+	}
+	return r
+}
+
+// networkingConfig builds the endpoint configuration of the network the
+// container is created on
+func (e *ContainerRunner) networkingConfig() *network.NetworkingConfig {
+	if len(e.networks) == 0 || e.noNetwork {
+		return nil
+	}
+	return &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			e.networks[0]: e.endpointSettings(e.networks[0]),
+		},
+	}
+}
+
+// endpointSettings builds the endpoint configuration of the named network
+func (e *ContainerRunner) endpointSettings(name string) *network.EndpointSettings {
+	return &network.EndpointSettings{
+		Aliases: e.networkAliases[name],
+	}
+}
+
+// connectNetworks connects the created container to every network but the
+// first, which it was created on
+func (e *ContainerRunner) connectNetworks(ctx context.Context) error {
+	if e.noNetwork {
+		return nil
+	}
+	for i := 1; i < len(e.networks); i++ {
+		name := e.networks[i]
+		e.logger.Infof("connecting container to network %v", name)
+		if err := e.client.NetworkConnect(ctx, name, e.id, e.endpointSettings(name)); err != nil {
+			return fmt.Errorf("connecting container to network %v: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// HostGatewayIP returns the gateway of the container's network, which is the
+// host's address as seen from inside the container. The container can reach
+// services listening on the host at this address, provided they listen on the
+// bridge interface rather than only on localhost.
+func (e *ContainerRunner) HostGatewayIP(ctx context.Context) (string, error) {
+	// If we don't have a container id
+	if len(e.id) == 0 {
+		return "", ErrNoContainerId
+	}
+
+	info, err := e.client.ContainerInspect(ctx, e.id)
+	if err != nil {
+		return "", fmt.Errorf("inspecting container: %w", err)
+	}
+	if info.NetworkSettings == nil {
+		return "", errors.New("container has no network settings")
+	}
+	names := append([]string{}, e.networks...)
+	names = append(names, "bridge")
+	for _, name := range names {
+		if endpoint, ok := info.NetworkSettings.Networks[name]; ok && endpoint != nil && len(endpoint.Gateway) > 0 {
+			return endpoint.Gateway, nil
+		}
+	}
+	for _, endpoint := range info.NetworkSettings.Networks {
+		if endpoint != nil && len(endpoint.Gateway) > 0 {
+			return endpoint.Gateway, nil
+		}
+	}
+	if len(info.NetworkSettings.Gateway) > 0 {
+		return info.NetworkSettings.Gateway, nil
+	}
+	return "", errors.New("container has no gateway")
+}
+
+// containsString returns true if s is a member of slice
+func containsString(slice []string, s string) bool {
+	for _, member := range slice {
+		if member == s {
+			return true
+		}
+	}
+	return false
+}
+
+// NetworkOptions configures a network created with CreateNetwork. Empty
+// fields keep Docker's defaults.
+type NetworkOptions struct {
+	// Driver is the network driver, e.g. "bridge", "overlay" or "macvlan"
+	Driver string
+	// Subnet is the subnet of the network in CIDR notation, e.g.
+	// "172.28.0.0/16"
+	Subnet string
+	// Gateway is the gateway of the subnet, e.g. "172.28.0.1". It requires
+	// Subnet.
+	Gateway string
+	// Internal disables access to external networks
+	Internal bool
+	// Labels are added to the network
+	Labels map[string]string
+}
+
+// validate returns an error if the subnet or the gateway are invalid
+func (o NetworkOptions) validate() error {
+	if len(o.Subnet) == 0 {
+		if len(o.Gateway) > 0 {
+			return errors.New("a gateway requires a subnet")
+		}
+		return nil
+	}
+	_, subnet, err := net.ParseCIDR(o.Subnet)
+	if err != nil {
+		return fmt.Errorf("invalid subnet %q: %w", o.Subnet, err)
+	}
+	if len(o.Gateway) > 0 {
+		gateway := net.ParseIP(o.Gateway)
+		if gateway == nil {
+			return fmt.Errorf("invalid gateway %q", o.Gateway)
+		}
+		if !subnet.Contains(gateway) {
+			return fmt.Errorf("gateway %v is not in subnet %v", o.Gateway, o.Subnet)
+		}
+	}
+	return nil
+}
+
+// CreateNetwork creates a network that containers can be attached to with
+// WithNetwork, and returns its id
+func CreateNetwork(ctx context.Context, name string, opts NetworkOptions) (string, error) {
+	c, err := client.NewEnvClient()
+	if err != nil {
+		return "", fmt.Errorf("creating env client: %w", err)
+	}
+	defer c.Close()
+	return createNetwork(ctx, c, name, opts)
+}
+
+// createNetwork creates the network using c
+func createNetwork(ctx context.Context, c client.NetworkAPIClient, name string, opts NetworkOptions) (string, error) {
+	if err := opts.validate(); err != nil {
+		return "", fmt.Errorf("invalid options for network %v: %w", name, err)
+	}
+	create := types.NetworkCreate{
+		CheckDuplicate: true,
+		Driver:         opts.Driver,
+		Internal:       opts.Internal,
+		Labels:         opts.Labels,
+	}
+	if len(opts.Subnet) > 0 {
+		create.IPAM = &network.IPAM{
+			Config: []network.IPAMConfig{{Subnet: opts.Subnet, Gateway: opts.Gateway}},
+		}
+	}
+	resp, err := c.NetworkCreate(ctx, name, create)
+	if err != nil {
+		return "", fmt.Errorf("creating network %v: %w", name, err)
+	}
+	return resp.ID, nil
+}
+
+// RemoveNetwork removes the network with the given name or id
+func RemoveNetwork(ctx context.Context, name string) error {
+	c, err := client.NewEnvClient()
+	if err != nil {
+		return fmt.Errorf("creating env client: %w", err)
+	}
+	defer c.Close()
+	if err := c.NetworkRemove(ctx, name); err != nil {
+		return fmt.Errorf("removing network %v: %w", name, err)
+	}
+	return nil
+}
+
+// EnsureNetwork returns the id of the network with the given name, creating
+// it with Docker's defaults if it doesn't exist. created reports whether the
+// network was created by this call. Concurrent calls with the same name are
+// safe: if another caller creates the network first, its id is returned.
+func EnsureNetwork(ctx context.Context, name string) (id string, created bool, err error) {
+	c, err := client.NewEnvClient()
+	if err != nil {
+		return "", false, fmt.Errorf("creating env client: %w", err)
+	}
+	defer c.Close()
+	return ensureNetwork(ctx, c, name)
+}
+
+// ensureNetwork looks up or creates the network using c
+func ensureNetwork(ctx context.Context, c client.NetworkAPIClient, name string) (string, bool, error) {
+	info, err := c.NetworkInspect(ctx, name)
+	if err == nil {
+		return info.ID, false, nil
+	}
+	if !client.IsErrNotFound(err) {
+		return "", false, fmt.Errorf("inspecting network %v: %w", name, err)
+	}
+
+	id, err := createNetwork(ctx, c, name, NetworkOptions{})
+	if err == nil {
+		return id, true, nil
+	}
+	if !strings.Contains(strings.ToLower(err.Error()), "already exists") {
+		return "", false, err
+	}
+	// Another caller created the network in the meantime
+	info, err = c.NetworkInspect(ctx, name)
+	if err != nil {
+		return "", false, fmt.Errorf("inspecting network %v: %w", name, err)
+	}
+	return info.ID, false, nil
+}
@@ -0,0 +1,138 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCreateNetwork(t *testing.T) {
+	var created types.NetworkCreate
+	c := &mockClient{
+		networkCreate: func(name string, options types.NetworkCreate) (types.NetworkCreateResponse, error) {
+			created = options
+			return types.NetworkCreateResponse{ID: name + "-id"}, nil
+		},
+	}
+
+	id, err := createNetwork(context.Background(), c, "backend", NetworkOptions{
+		Driver:   "bridge",
+		Subnet:   "172.28.0.0/16",
+		Gateway:  "172.28.0.1",
+		Internal: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "backend-id", id)
+	require.Equal(t, "bridge", created.Driver)
+	require.True(t, created.Internal)
+	require.Equal(t, []network.IPAMConfig{{Subnet: "172.28.0.0/16", Gateway: "172.28.0.1"}}, created.IPAM.Config)
+
+	var testCases = []struct {
+		name string
+		opts NetworkOptions
+	}{
+		{name: "invalid subnet", opts: NetworkOptions{Subnet: "172.28.0.0"}},
+		{name: "gateway outside subnet", opts: NetworkOptions{Subnet: "172.28.0.0/16", Gateway: "10.0.0.1"}},
+		{name: "gateway without subnet", opts: NetworkOptions{Gateway: "172.28.0.1"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := createNetwork(context.Background(), c, "backend", tc.opts)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestEnsureNetwork(t *testing.T) {
+	inspections := 0
+	c := &mockClient{
+		networkInspect: func(name string) (types.NetworkResource, error) {
+			inspections++
+			// The network appears after the first inspection, as if created
+			// by another caller
+			if inspections == 1 {
+				return types.NetworkResource{}, notFoundError{}
+			}
+			return types.NetworkResource{ID: "other-id", Name: name}, nil
+		},
+		networkCreate: func(name string, options types.NetworkCreate) (types.NetworkCreateResponse, error) {
+			return types.NetworkCreateResponse{}, errors.New("Error response from daemon: network with name backend already exists")
+		},
+	}
+
+	id, created, err := ensureNetwork(context.Background(), c, "backend")
+	require.NoError(t, err)
+	require.Equal(t, "other-id", id)
+	require.False(t, created)
+
+	c.networkInspect = func(name string) (types.NetworkResource, error) {
+		return types.NetworkResource{}, notFoundError{}
+	}
+	c.networkCreate = func(name string, options types.NetworkCreate) (types.NetworkCreateResponse, error) {
+		return types.NetworkCreateResponse{ID: "new-id"}, nil
+	}
+	id, created, err = ensureNetwork(context.Background(), c, "backend")
+	require.NoError(t, err)
+	require.Equal(t, "new-id", id)
+	require.True(t, created)
+}
+
+func TestHostGatewayIP(t *testing.T) {
+	runner := NewContainerRunner().WithImage("mongo").WithNetwork("backend")
+	runner.client = &mockClient{
+		containerInspect: func(id string) (types.ContainerJSON, error) {
+			info := runningContainer()
+			info.NetworkSettings = &types.NetworkSettings{
+				Networks: map[string]*network.EndpointSettings{
+					"other":   {Gateway: "172.19.0.1"},
+					"backend": {Gateway: "172.18.0.1"},
+				},
+			}
+			return info, nil
+		},
+	}
+	_, err := runner.HostGatewayIP(context.Background())
+	require.Equal(t, ErrNoContainerId, err)
+
+	require.NoError(t, runner.Start(context.Background()))
+	ip, err := runner.HostGatewayIP(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "172.18.0.1", ip)
+}
+
+func TestWithHostGatewayAlias(t *testing.T) {
+	runner := NewContainerRunner().WithHostGatewayAlias("host").WithHostGatewayAlias("host")
+	require.NoError(t, runner.err)
+	require.Equal(t, []string{"host:host-gateway"}, runner.hostConfig().ExtraHosts)
+
+	runner.WithHostGatewayAlias("host:1")
+	require.Error(t, runner.err)
+}
+
+func TestWithNetworkAlias(t *testing.T) {
+	connected := map[string][]string{}
+	runner := NewContainerRunner().
+		WithImage("mongo").
+		WithNetworkAlias("backend", "db", "mongo").
+		WithNetworkAlias("backend", "mongo", "primary").
+		WithNetworkAlias("metrics", "exporter").
+		WithNetworkAlias("metrics", "mongo-exporter")
+	runner.client = &mockClient{
+		networkConnect: func(name, id string, config *network.EndpointSettings) error {
+			connected[name] = config.Aliases
+			return nil
+		},
+	}
+
+	// The container is created on the first network and connected to the
+	// others
+	config := runner.networkingConfig()
+	require.Equal(t, []string{"db", "mongo", "primary"}, config.EndpointsConfig["backend"].Aliases)
+	require.NotContains(t, config.EndpointsConfig, "metrics")
+
+	require.NoError(t, runner.Start(context.Background()))
+	require.Equal(t, map[string][]string{"metrics": {"exporter", "mongo-exporter"}}, connected)
+}
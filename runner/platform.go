@@ -0,0 +1,73 @@
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrPlatformMismatch is returned by Start with WithStrictPlatform if the
+// platform of the image does not match the platform of the daemon
+var ErrPlatformMismatch = errors.New("image platform does not match the host platform")
+
+// platformMismatch is the fragment of the warning Docker emits when an image
+// of another platform is pulled or run, e.g. an amd64 image on an ARM host
+const platformMismatch = "does not match the detected host platform"
+
+// WithStrictPlatform makes Start fail with ErrPlatformMismatch when Docker
+// warns that the image's platform does not match the host's, instead of only
+// logging the warning. Such containers run under emulation, which is slow.
+func (r *ContainerRunner) WithStrictPlatform(strict bool) *ContainerRunner {
+	r.strictPlatform = strict
+	return r
+}
+
+// checkWarnings logs the warnings Docker returned for an operation, and
+// returns ErrPlatformMismatch for a platform mismatch with WithStrictPlatform
+func (e *ContainerRunner) checkWarnings(warnings []string) error {
+	for _, warning := range warnings {
+		if !strings.Contains(warning, platformMismatch) {
+			e.logger.Warnln(warning)
+			continue
+		}
+		if err := e.platformMismatch(warning); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// platformMismatch reports a platform mismatch warning
+func (e *ContainerRunner) platformMismatch(warning string) error {
+	if e.strictPlatform {
+		return fmt.Errorf("%w: %v", ErrPlatformMismatch, warning)
+	}
+	e.logger.Warnf("%v, the container will run under emulation", warning)
+	return nil
+}
+
+// platformDetector is written the progress stream of a pull and records
+// whether it warned about a platform mismatch
+type platformDetector struct {
+	// tail holds the end of the previous write, so that a warning split
+	// across writes is detected
+	tail  []byte
+	found bool
+}
+
+func (d *platformDetector) Write(p []byte) (int, error) {
+	if d.found {
+		return len(p), nil
+	}
+	data := append(d.tail, p...)
+	if bytes.Contains(data, []byte(platformMismatch)) {
+		d.found = true
+		return len(p), nil
+	}
+	if len(data) > len(platformMismatch) {
+		data = data[len(data)-len(platformMismatch):]
+	}
+	d.tail = append([]byte(nil), data...)
+	return len(p), nil
+}
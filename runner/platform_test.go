@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestWithStrictPlatform(t *testing.T) {
+	warning := "WARNING: The requested image's platform (linux/amd64) does not match the detected host platform (linux/arm64/v8) and no specific platform was requested"
+
+	runner := NewContainerRunner().
+		WithImage("mssql").
+		WithStrictPlatform(true)
+	runner.client = &mockClient{
+		imagePull: func(ref string) (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader(`{"status":"Pulling from library/mssql"}` + "\n" + `{"status":"` + warning + `"}` + "\n")), nil
+		},
+	}
+	err := runner.Start(context.Background())
+	require.True(t, errors.Is(err, ErrPlatformMismatch))
+
+	var removed bool
+	runner = NewContainerRunner().
+		WithImage("mssql").
+		WithStrictPlatform(true)
+	runner.client = &mockClient{
+		containerCreate: func(name string) (container.ContainerCreateCreatedBody, error) {
+			return container.ContainerCreateCreatedBody{ID: "id", Warnings: []string{warning}}, nil
+		},
+		containerRemove: func(id string, options types.ContainerRemoveOptions) error {
+			removed = true
+			return nil
+		},
+	}
+	err = runner.Start(context.Background())
+	require.True(t, errors.Is(err, ErrPlatformMismatch))
+	require.True(t, removed)
+
+	runner = NewContainerRunner().WithImage("mssql")
+	runner.client = &mockClient{
+		containerCreate: func(name string) (container.ContainerCreateCreatedBody, error) {
+			return container.ContainerCreateCreatedBody{ID: "id", Warnings: []string{warning}}, nil
+		},
+	}
+	require.NoError(t, runner.Start(context.Background()))
+}
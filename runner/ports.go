@@ -0,0 +1,187 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"github.com/docker/go-connections/nat"
+	"net"
+	"strconv"
+)
+
+// WithPortBindings exposes containerPort and binds it to each of the given
+// host addresses, e.g. both on loopback and on a LAN address, replacing any
+// bindings made for it before. An empty HostPort lets Docker pick a free port.
+// The same host address and port cannot be bound twice.
+func (r *ContainerRunner) WithPortBindings(containerPort int, bindings ...nat.PortBinding) *ContainerRunner {
+	if err := r.bindPort(nat.Port(strconv.Itoa(containerPort)), bindings); err != nil {
+		r.setErr(err)
+	}
+	return r
+}
+
+// WithPortMappings exposes and binds ports given in the syntax of `docker run
+// -p`: "[[ip:]host:]container[/protocol]", e.g. "15432:5432",
+// "127.0.0.1:8080:80" or "53:53/udp". Unlike docker run, a mapping without an
+// address is bound on DefaultHostAddress, as with WithPorts, and one without a
+// host port on a free port picked by Docker. Like WithPortBindings, this
+// replaces the bindings previously made for the same container ports.
+func (r *ContainerRunner) WithPortMappings(specs ...string) *ContainerRunner {
+	var ports []nat.Port
+	bindings := map[nat.Port][]nat.PortBinding{}
+	for _, spec := range specs {
+		mappings, err := nat.ParsePortSpec(spec)
+		if err != nil {
+			r.setErr(fmt.Errorf("invalid port mapping %q: %w", spec, err))
+			return r
+		}
+		for _, m := range mappings {
+			port := m.Port
+			if port.Proto() == "tcp" {
+				// Like WithPorts, so that both name the same port
+				port = nat.Port(port.Port())
+			}
+			if len(m.Binding.HostIP) == 0 {
+				m.Binding.HostIP = DefaultHostAddress
+			}
+			if _, ok := bindings[port]; !ok {
+				ports = append(ports, port)
+			}
+			bindings[port] = append(bindings[port], m.Binding)
+		}
+	}
+	for _, port := range ports {
+		if err := r.bindPort(port, bindings[port]); err != nil {
+			r.setErr(err)
+			return r
+		}
+	}
+	return r
+}
+
+// bindPort exposes port and replaces its bindings, rejecting host addresses
+// that are already bound for the same protocol
+func (r *ContainerRunner) bindPort(port nat.Port, bindings []nat.PortBinding) error {
+	if len(bindings) == 0 {
+		return fmt.Errorf("port %v: at least one binding is required", port)
+	}
+	seen := map[string]bool{}
+	for other, existing := range r.portBindings {
+		if other == port || other.Proto() != port.Proto() {
+			continue
+		}
+		for _, b := range existing {
+			seen[net.JoinHostPort(b.HostIP, b.HostPort)] = true
+		}
+	}
+	for _, b := range bindings {
+		if len(b.HostPort) == 0 || b.HostPort == "0" {
+			continue
+		}
+		address := net.JoinHostPort(b.HostIP, b.HostPort)
+		if seen[address] {
+			return fmt.Errorf("port %v: host address %v is bound twice", port, address)
+		}
+		seen[address] = true
+	}
+
+	if _, ok := r.exposedPorts[port]; !ok {
+		r.ports = append(r.ports, string(port))
+		r.exposedPorts[port] = struct{}{}
+	}
+	r.portBindings[port] = append([]nat.PortBinding(nil), bindings...)
+	return nil
+}
+
+// WithPublishAll publishes every port that the image exposes to a random host
+// port, like `docker run -P`. Ports added with WithPorts or WithPortBindings
+// keep their bindings. The resulting mappings can be read with PortMappings.
+func (r *ContainerRunner) WithPublishAll(publish bool) *ContainerRunner {
+	r.publishAll = publish
+	return r
+}
+
+// HostPort returns the host port that the daemon bound to containerPort. The
+// port is resolved from the running container so that host ports that were
+// auto-assigned by Docker (host port 0) are reported correctly.
+func (e *ContainerRunner) HostPort(ctx context.Context, containerPort int) (string, error) {
+	binding, err := e.hostBinding(ctx, containerPort)
+	if err != nil {
+		return "", err
+	}
+	return binding.HostPort, nil
+}
+
+// Endpoint returns the dialable "host:port" address of containerPort, suitable
+// for passing straight to net.Dial.
+func (e *ContainerRunner) Endpoint(ctx context.Context, containerPort int) (string, error) {
+	binding, err := e.hostBinding(ctx, containerPort)
+	if err != nil {
+		return "", err
+	}
+	host := binding.HostIP
+	if len(host) == 0 || host == "0.0.0.0" {
+		host = DefaultHostAddress
+	}
+	return net.JoinHostPort(host, binding.HostPort), nil
+}
+
+// PortMappings returns every container port that the daemon bound to a host
+// port, mapped to that host port. A container port that is bound more than
+// once, such as on several host addresses, is mapped to its first binding.
+func (e *ContainerRunner) PortMappings(ctx context.Context) (map[int]int, error) {
+	// If we don't have a container id
+	if len(e.id) == 0 {
+		return nil, ErrNoContainerId
+	}
+
+	info, err := e.client.ContainerInspect(ctx, e.id)
+	if err != nil {
+		return nil, fmt.Errorf("inspecting container: %w", err)
+	}
+	mappings := map[int]int{}
+	if info.NetworkSettings == nil {
+		return mappings, nil
+	}
+	for port, bindings := range info.NetworkSettings.Ports {
+		if _, ok := mappings[port.Int()]; ok {
+			continue
+		}
+		for _, b := range bindings {
+			hostPort, err := strconv.Atoi(b.HostPort)
+			if err != nil || hostPort == 0 {
+				continue
+			}
+			mappings[port.Int()] = hostPort
+			break
+		}
+	}
+	return mappings, nil
+}
+
+// hostBinding inspects the container and returns the first binding of
+// containerPort that has a host port assigned
+func (e *ContainerRunner) hostBinding(ctx context.Context, containerPort int) (nat.PortBinding, error) {
+	// If we don't have a container id
+	if len(e.id) == 0 {
+		return nat.PortBinding{}, ErrNoContainerId
+	}
+
+	info, err := e.client.ContainerInspect(ctx, e.id)
+	if err != nil {
+		return nat.PortBinding{}, fmt.Errorf("inspecting container: %w", err)
+	}
+	if info.NetworkSettings == nil {
+		return nat.PortBinding{}, fmt.Errorf("port %v: %w", containerPort, ErrPortNotBound)
+	}
+
+	port, err := nat.NewPort("tcp", strconv.Itoa(containerPort))
+	if err != nil {
+		return nat.PortBinding{}, fmt.Errorf("parsing port %v: %w", containerPort, err)
+	}
+	for _, b := range info.NetworkSettings.Ports[port] {
+		if len(b.HostPort) > 0 && b.HostPort != "0" {
+			return b, nil
+		}
+	}
+	return nat.PortBinding{}, fmt.Errorf("port %v: %w", containerPort, ErrPortNotBound)
+}
@@ -0,0 +1,168 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPortMappings(t *testing.T) {
+	runner := NewContainerRunner()
+	runner.id = "abc"
+	runner.client = &mockClient{
+		containerInspect: func(id string) (types.ContainerJSON, error) {
+			info := runningContainer()
+			info.NetworkSettings = &types.NetworkSettings{
+				NetworkSettingsBase: types.NetworkSettingsBase{
+					Ports: nat.PortMap{
+						"5432/tcp": {{HostIP: "0.0.0.0", HostPort: "32768"}},
+						"6379/tcp": {{HostIP: "0.0.0.0", HostPort: "32769"}, {HostIP: "::", HostPort: "32769"}},
+						"8080/tcp": nil,
+					},
+				},
+			}
+			return info, nil
+		},
+	}
+
+	mappings, err := runner.PortMappings(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, map[int]int{5432: 32768, 6379: 32769}, mappings)
+}
+
+func TestEndpoint(t *testing.T) {
+	runner := NewContainerRunner()
+	runner.id = "abc"
+	runner.client = &mockClient{
+		containerInspect: func(id string) (types.ContainerJSON, error) {
+			info := runningContainer()
+			info.NetworkSettings = &types.NetworkSettings{
+				NetworkSettingsBase: types.NetworkSettingsBase{
+					Ports: nat.PortMap{
+						"5432/tcp": {{HostIP: "0.0.0.0", HostPort: "32768"}},
+						"6379/tcp": {{HostPort: "32769"}},
+						"8080/tcp": {{HostIP: "::1", HostPort: "32770"}},
+					},
+				},
+			}
+			return info, nil
+		},
+	}
+
+	for port, endpoint := range map[int]string{
+		5432: net.JoinHostPort(DefaultHostAddress, "32768"),
+		6379: net.JoinHostPort(DefaultHostAddress, "32769"),
+		8080: "[::1]:32770",
+	} {
+		got, err := runner.Endpoint(context.Background(), port)
+		require.NoError(t, err)
+		require.Equal(t, endpoint, got)
+	}
+}
+
+func TestWithPortBindings(t *testing.T) {
+	runner := NewContainerRunner().
+		WithPorts(5432).
+		WithPortBindings(8080,
+			nat.PortBinding{HostIP: "127.0.0.1", HostPort: "8080"},
+			nat.PortBinding{HostIP: "192.168.1.10", HostPort: "8080"},
+		)
+	require.NoError(t, runner.err)
+	require.Equal(t, []nat.PortBinding{
+		{HostIP: "127.0.0.1", HostPort: "8080"},
+		{HostIP: "192.168.1.10", HostPort: "8080"},
+	}, runner.hostConfig().PortBindings["8080"])
+	require.Contains(t, runner.containerConfig().ExposedPorts, nat.Port("8080"))
+
+	runner = NewContainerRunner().
+		WithPorts(5432).
+		WithPortBindings(5433, nat.PortBinding{HostIP: DefaultHostAddress, HostPort: "5432"})
+	require.Error(t, runner.err)
+
+	runner = NewContainerRunner().WithPortBindings(8080,
+		nat.PortBinding{HostIP: "127.0.0.1", HostPort: "8080"},
+		nat.PortBinding{HostIP: "127.0.0.1", HostPort: "8080"},
+	)
+	require.Error(t, runner.err)
+}
+
+func TestWithPublishAll(t *testing.T) {
+	runner := NewContainerRunner().
+		WithImage("nginx").
+		WithPorts(80).
+		WithPublishAll(true)
+	hostConfig := runner.hostConfig()
+	require.True(t, hostConfig.PublishAllPorts)
+	require.Equal(t, []nat.PortBinding{{HostIP: DefaultHostAddress, HostPort: "80"}}, hostConfig.PortBindings["80"])
+
+	require.False(t, runner.WithNoNetwork(true).hostConfig().PublishAllPorts)
+}
+
+func TestWithPortMappings(t *testing.T) {
+	runner := NewContainerRunner().
+		WithPortMappings("15432:5432", "127.0.0.1:8080:80", "0.0.0.0:8443:80", "53:53/udp", "53:53", "9000")
+	require.NoError(t, runner.err)
+	require.Equal(t, nat.PortMap{
+		"5432":   {{HostIP: DefaultHostAddress, HostPort: "15432"}},
+		"80":     {{HostIP: "127.0.0.1", HostPort: "8080"}, {HostIP: "0.0.0.0", HostPort: "8443"}},
+		"53/udp": {{HostIP: DefaultHostAddress, HostPort: "53"}},
+		"53":     {{HostIP: DefaultHostAddress, HostPort: "53"}},
+		"9000":   {{HostIP: DefaultHostAddress}},
+	}, runner.portBindings)
+	require.Contains(t, runner.exposedPorts, nat.Port("53/udp"))
+
+	for _, spec := range []string{"", "abc", "8080:http", "256.0.0.1:80:80", "53:53/icmp"} {
+		runner := NewContainerRunner().WithPortMappings(spec)
+		require.Error(t, runner.err, spec)
+	}
+
+	runner = NewContainerRunner().WithPortMappings("8080:80", "8080:81")
+	require.Error(t, runner.err)
+}
+
+func TestWaitForPortClosed(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	_, hostPort, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+
+	runner := NewContainerRunner()
+	runner.id = "abc"
+	runner.client = &mockClient{
+		containerInspect: func(id string) (types.ContainerJSON, error) {
+			info := runningContainer()
+			info.NetworkSettings = &types.NetworkSettings{
+				NetworkSettingsBase: types.NetworkSettingsBase{
+					Ports: nat.PortMap{
+						"8080/tcp": {{HostIP: "127.0.0.1", HostPort: hostPort}},
+					},
+				},
+			}
+			return info, nil
+		},
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	err = runner.WaitForPortClosed(context.Background(), 8080, 100*time.Millisecond)
+	require.True(t, errors.Is(err, ErrWaitTimeout))
+
+	time.AfterFunc(100*time.Millisecond, func() { listener.Close() })
+	require.NoError(t, runner.WaitForPortClosed(context.Background(), 8080, 5*time.Second))
+
+	// The container exited and its port is no longer bound
+	require.NoError(t, runner.WaitForPortClosed(context.Background(), 5432, time.Second))
+}
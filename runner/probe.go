@@ -0,0 +1,100 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultProbeImage is the image of the throwaway container used by
+// ForNetworkPort, which needs sh and nc
+const DefaultProbeImage = "busybox"
+
+// ForNetworkPort is ready once containerPort accepts TCP connections from
+// inside the container's network, which doesn't require the port to be
+// published to the host. It runs a throwaway DefaultProbeImage container on
+// the same network that dials the port until it succeeds, and removes it
+// afterwards. A zero timeout means DefaultWaitTimeout.
+func ForNetworkPort(containerPort int, timeout time.Duration) WaitStrategy {
+	return &networkPortStrategy{port: containerPort, timeout: timeout}
+}
+
+type networkPortStrategy struct {
+	port    int
+	timeout time.Duration
+}
+
+func (s *networkPortStrategy) String() string {
+	return fmt.Sprintf("port %v in network", s.port)
+}
+
+func (s *networkPortStrategy) WaitUntilReady(ctx context.Context, r *ContainerRunner) error {
+	if r.noNetwork {
+		return errors.New("the container has no network")
+	}
+	network, ip, err := r.networkAddress(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, orDefaultTimeout(s.timeout))
+	defer cancel()
+
+	script := fmt.Sprintf("until nc -z -w 1 %v %v; do sleep 0.1; done", ip, s.port)
+	probe := NewContainerRunner().
+		WithImage(DefaultProbeImage).
+		WithPullPolicy(PullIfNotPresent).
+		WithLogger(r.logger).
+		WithCommand("/bin/sh", "-c", script)
+	if network != "bridge" {
+		probe.WithNetwork(network)
+	}
+	probe.client = r.client
+	defer func() {
+		// The probe may still be running if ctx expired
+		if len(probe.id) == 0 {
+			return
+		}
+		if err := probe.Stop(context.Background(), StopTimeout(0), StopRemove(true), StopForce(true)); err != nil {
+			r.logger.Warnf("removing probe container: %v", err)
+		}
+	}()
+
+	if err := probe.Start(ctx); err != nil {
+		return timeoutErr(ctx, fmt.Errorf("starting probe container: %w", err))
+	}
+	code, err := probe.WaitForExit(ctx)
+	if err != nil {
+		return timeoutErr(ctx, err)
+	}
+	if code != 0 {
+		return fmt.Errorf("probe container: %w", &ExitError{Code: code})
+	}
+	return nil
+}
+
+// networkAddress returns a network of the container and its IP address on
+// that network, preferring the network the container was created on
+func (e *ContainerRunner) networkAddress(ctx context.Context) (string, string, error) {
+	info, err := e.client.ContainerInspect(ctx, e.id)
+	if err != nil {
+		return "", "", fmt.Errorf("inspecting container: %w", err)
+	}
+	if info.NetworkSettings == nil {
+		return "", "", errors.New("container has no network settings")
+	}
+	names := append([]string{}, e.networks...)
+	names = append(names, "bridge")
+	for _, name := range names {
+		if endpoint, ok := info.NetworkSettings.Networks[name]; ok && len(endpoint.IPAddress) > 0 {
+			return name, endpoint.IPAddress, nil
+		}
+	}
+	for name, endpoint := range info.NetworkSettings.Networks {
+		if len(endpoint.IPAddress) > 0 {
+			return name, endpoint.IPAddress, nil
+		}
+	}
+	return "", "", errors.New("container has no ip address")
+}
@@ -0,0 +1,45 @@
+package runner
+
+import (
+	"context"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestForNetworkPort(t *testing.T) {
+	var created, removed []string
+	runner := NewContainerRunner().
+		WithImage("postgres").
+		WithNetwork("backend").
+		WithWaitForNetworkPort(5432, 0)
+	runner.client = &mockClient{
+		containerCreate: func(name string) (container.ContainerCreateCreatedBody, error) {
+			id := "probe"
+			if len(created) == 0 {
+				id = "postgres"
+			}
+			created = append(created, id)
+			return container.ContainerCreateCreatedBody{ID: id}, nil
+		},
+		containerInspect: func(id string) (types.ContainerJSON, error) {
+			info := runningContainer()
+			info.NetworkSettings = &types.NetworkSettings{
+				Networks: map[string]*network.EndpointSettings{
+					"backend": {IPAddress: "172.18.0.2"},
+				},
+			}
+			return info, nil
+		},
+		containerRemove: func(id string, options types.ContainerRemoveOptions) error {
+			removed = append(removed, id)
+			return nil
+		},
+	}
+
+	require.NoError(t, runner.Start(context.Background()))
+	require.Equal(t, []string{"postgres", "probe"}, created)
+	require.Equal(t, []string{"probe"}, removed)
+}
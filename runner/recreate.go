@@ -0,0 +1,32 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+)
+
+// Recreate replaces the container with a fresh one whose configuration was
+// changed by mutate, e.g. to test how a service reloads its configuration.
+// The current container is stopped and force removed, then mutate is applied
+// to the runner and a new container is started. Named volumes, bind mounts
+// and networks are kept, so state carries over to the new container. A new
+// container is started even if the old one could not be removed, and the
+// failures of both phases are returned together as a MultiError.
+func (e *ContainerRunner) Recreate(ctx context.Context, mutate func(*ContainerRunner)) error {
+	var stopErr error
+	if len(e.id) > 0 {
+		if err := e.Stop(ctx, StopRemove(true), StopForce(true)); err != nil {
+			stopErr = fmt.Errorf("removing old container: %w", err)
+		}
+	}
+	if mutate != nil {
+		mutate(e)
+	}
+	e.Reset()
+
+	var startErr error
+	if err := e.Start(ctx); err != nil {
+		startErr = fmt.Errorf("starting new container: %w", err)
+	}
+	return newMultiError([]error{stopErr, startErr})
+}
@@ -0,0 +1,46 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRecreate(t *testing.T) {
+	var created []string
+	runner := NewContainerRunner().
+		WithImage("nginx").
+		WithEnvironmentVariable("MODE", "old")
+	runner.client = &mockClient{
+		containerCreate: func(name string) (container.ContainerCreateCreatedBody, error) {
+			created = append(created, runner.env[0])
+			return container.ContainerCreateCreatedBody{ID: runner.env[0]}, nil
+		},
+	}
+	require.NoError(t, runner.Start(context.Background()))
+
+	err := runner.Recreate(context.Background(), func(r *ContainerRunner) {
+		r.WithEnvironmentVariable("MODE", "new")
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"MODE=old", "MODE=new"}, created)
+	require.Equal(t, "MODE=new", runner.id)
+
+	failing := errors.New("daemon hiccup")
+	runner.client.(*mockClient).containerStop = func(ctx context.Context, id string) error {
+		return failing
+	}
+	runner.client.(*mockClient).containerRemove = func(id string, options types.ContainerRemoveOptions) error {
+		return failing
+	}
+	runner.client.(*mockClient).containerStart = func(id string) error {
+		return errors.New("port is already allocated")
+	}
+	err = runner.Recreate(context.Background(), nil)
+	require.True(t, errors.Is(err, failing))
+	require.True(t, errors.Is(err, ErrPortInUse))
+	require.Len(t, err.(MultiError), 2)
+}
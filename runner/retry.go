@@ -0,0 +1,38 @@
+package runner
+
+import (
+	"errors"
+)
+
+// WithRetryableError adds a predicate for errors that are retried, in
+// addition to the transient errors retried by default: the mounts of a
+// container being busy while it is removed, and the connection to the daemon
+// dropping while WaitForExit waits. This is useful for failures specific to
+// an environment, such as a proxy in front of the daemon that responds with
+// 503. Retries keep their attempts and backoff, see DefaultRemoveAttempts and
+// DefaultWaitAttempts.
+func (r *ContainerRunner) WithRetryableError(retryable func(err error) bool) *ContainerRunner {
+	if retryable == nil {
+		r.setErr(errors.New("retryable error predicate must not be nil"))
+		return r
+	}
+	r.retryable = append(r.retryable, retryable)
+	return r
+}
+
+// isRetryable returns true if err is retried by default, as decided by
+// builtin, or by a predicate added with WithRetryableError
+func (e *ContainerRunner) isRetryable(err error, builtin func(error) bool) bool {
+	if err == nil {
+		return false
+	}
+	if builtin(err) {
+		return true
+	}
+	for _, retryable := range e.retryable {
+		if retryable(err) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithRetryableError(t *testing.T) {
+	defer func(backoff time.Duration) { removeBackoff = backoff }(removeBackoff)
+	removeBackoff = time.Millisecond
+
+	unavailable := errors.New("Error response from proxy: 503 Service Unavailable")
+	removals := 0
+	runner := NewContainerRunner().WithImage("mongo")
+	runner.client = &mockClient{
+		containerRemove: func(id string, options types.ContainerRemoveOptions) error {
+			removals++
+			if removals == 1 {
+				return unavailable
+			}
+			return nil
+		},
+	}
+	require.NoError(t, runner.Start(context.Background()))
+	require.Error(t, runner.Stop(context.Background()))
+	require.Equal(t, 1, removals)
+
+	removals = 0
+	runner.WithRetryableError(func(err error) bool {
+		return strings.Contains(err.Error(), "503")
+	})
+	require.NoError(t, runner.Stop(context.Background()))
+	require.Equal(t, 2, removals)
+
+	runner.WithRetryableError(nil)
+	require.Error(t, runner.err)
+}
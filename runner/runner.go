@@ -6,44 +6,134 @@ import (
 	"fmt"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/docker/go-connections/nat"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 )
 
 const (
-	DefaultHostAddress = "127.0.0.1"
+	DefaultHostAddress  = "127.0.0.1"
+	DefaultDockerSocket = "/var/run/docker.sock"
+	// DefaultRemoveAttempts is the maximum number of times a container is
+	// removed while its mounts are reported busy
+	DefaultRemoveAttempts = 5
+	// DefaultRemoveBackoff is the delay before the first removal retry. It
+	// doubles with every attempt.
+	DefaultRemoveBackoff = 200 * time.Millisecond
 )
 
 var (
 	RegistryExtensionOptions = []string{".com", ".io", ".org", ".net"}
 	DefaultContainerName     = uuid.New().String()
 	ErrNoContainerId         = errors.New("container id does not exist")
+	ErrDockerHostNotUnix     = errors.New("docker host is not a unix socket")
+	ErrPortNotBound          = errors.New("container port is not bound to a host port")
+	ErrRemovalTimeout        = errors.New("timed out waiting for container removal")
+	ErrDaemonUnreachable     = errors.New("docker daemon not reachable")
+	ErrWaitTimeout           = errors.New("timed out waiting for container")
+	ErrLogsEnded             = errors.New("container logs ended")
+	ErrContainerExited       = errors.New("container exited")
+	ErrNoHealthcheck         = errors.New("container has no healthcheck")
+	ErrUnknownRuntime        = errors.New("runtime is not configured on the docker daemon")
+	ErrStopTimeout           = errors.New("timed out waiting for container to stop")
+	ErrUnknownVolumeDriver   = errors.New("volume driver is not available on the docker daemon")
 )
 
+// removeBackoff is the delay before the first removal retry, which tests
+// shorten
+var removeBackoff = DefaultRemoveBackoff
+
 // ContainerRunnerInterface describes something that can start and stop containers
 type ContainerRunnerInterface interface {
 	Start(context.Context) error
-	Stop(context.Context) error
+	Stop(context.Context, ...StopOption) error
 }
 
 // ContainerRunner implements ContainerRunnerInterface and can construct a custom
 // container with image and port options
 type ContainerRunner struct {
-	name         string
-	image        string
-	ports        []string
-	env          []string
-	exposedPorts nat.PortSet
-	portBindings nat.PortMap
-	opts         *ContainerRunnerOpts
-	client       *client.Client
+	name           string
+	namePrefix     string
+	image          string
+	ports          []string
+	env            []string
+	fileEnv        []string
+	explicitEnv    []string
+	envTemplates   []envTemplate
+	templateEnv    []string
+	secretEnv      map[string]struct{}
+	binds          []string
+	mounts         []mount.Mount
+	sysctls        map[string]string
+	metadata       map[string]string
+	macAddress     string
+	dnsOptions     []string
+	extraHosts     []string
+	workdir        string
+	cmd            []string
+	entrypoint     []string
+	keepImageCmd   bool
+	imageCmd       []string
+	output         io.Writer
+	stdout         io.Writer
+	stderr         io.Writer
+	forceRecreate  bool
+	startDelay     time.Duration
+	imageTarball   string
+	build          *buildSpec
+	buildArgs      map[string]*string
+	pullPolicy     PullPolicy
+	strictPlatform bool
+	imagePulled    bool
+	registryAuths  map[string]types.AuthConfig
+	waitStrategies []WaitStrategy
+	healthcheck    *container.HealthConfig
+	readiness      ReadinessResult
+	// attempts counts the condition checks of wait strategies, atomically
+	// since strategies may be waited on from several goroutines
+	attempts       int32
+	networks       []string
+	networkAliases map[string][]string
+	labels         map[string]string
+	runtime        string
+	isolation      string
+	restartPolicy  container.RestartPolicy
+	stopSignal     string
+	logConfig      container.LogConfig
+	oomScoreAdj    int
+	attach         bool
+	noNetwork      bool
+	pidMode        string
+	ipcMode        string
+	resources      container.Resources
+	exposedPorts   nat.PortSet
+	portBindings   nat.PortMap
+	publishAll     bool
+	opts           *ContainerRunnerOpts
+	client         client.CommonAPIClient
+	clientTimeout  time.Duration
+	baseCtx        context.Context
+	retryable      []func(error) bool
+	logger         log.FieldLogger
+	logFields      log.Fields
 	// id managed by the runner itself
 	id string
+	// removed is set once the container was removed, which makes further
+	// calls to Stop a no-op
+	removed bool
+	// err records the first invalid option passed to the builder and is
+	// returned by Start
+	err error
 }
 
 // ContainerRunnerOpts allows customization of the runner's behavior
@@ -51,15 +141,50 @@ type ContainerRunnerOpts struct {
 	// If RemoveOnFinalization is enabled, the container will be removed
 	// after it is stopped.
 	RemoveOnFinalization bool
+
+	// If RemoveVolumesOnFinalization is enabled, the anonymous volumes that
+	// were created for the container are removed along with it. Named
+	// volumes are never removed by Docker this way, so data that was
+	// mounted into the container by name is kept. This only has an effect
+	// when RemoveOnFinalization is also enabled.
+	RemoveVolumesOnFinalization bool
+
+	// If ForceRemoveOnFailure is enabled and the container cannot be stopped
+	// or removed gracefully, removal is retried with Force set, which kills
+	// the container if it is still running. This only has an effect when
+	// RemoveOnFinalization is also enabled.
+	ForceRemoveOnFailure bool
+
+	// If KeepOnFailure is enabled, containers started with StartForTest are
+	// not removed when the test failed, even if RemoveOnFinalization is
+	// enabled, so that they can be inspected after the run. Setting the
+	// CONTAINER_KEEP environment variable to 1 has the same effect.
+	KeepOnFailure bool
+
+	// If DisableAutoLabels is enabled, the LabelManaged and LabelRunID
+	// labels are not added to the container, which also hides it from
+	// PruneOrphans.
+	DisableAutoLabels bool
+
+	// If DetectLeaks is enabled, the started container is recorded until it
+	// is stopped, so that LeakCheck can report it if it is left running.
+	DetectLeaks bool
 }
 
 // NewContainerRunner builds a runner that can be used to start and stop
 // containers using the locally installed docker engine
 func NewContainerRunner() *ContainerRunner {
 	return &ContainerRunner{
-		exposedPorts: map[nat.Port]struct{}{},
-		portBindings: map[nat.Port][]nat.PortBinding{},
-		env:          []string{},
+		exposedPorts:   map[nat.Port]struct{}{},
+		portBindings:   map[nat.Port][]nat.PortBinding{},
+		env:            []string{},
+		sysctls:        map[string]string{},
+		metadata:       map[string]string{},
+		networkAliases: map[string][]string{},
+		labels:         map[string]string{},
+		output:         ioutil.Discard,
+		logger:         log.StandardLogger(),
+		pullPolicy:     PullAlways,
 		opts: &ContainerRunnerOpts{
 			RemoveOnFinalization: true,
 		},
@@ -84,7 +209,9 @@ func (r *ContainerRunner) WithPorts(ports ...int) *ContainerRunner {
 }
 
 // WithImage sets the container image that should be used. It defaults to
-// the docker registry.
+// the docker registry: references that don't mention a registry domain are
+// prefixed with docker.io/library/, so "mongo" becomes
+// "docker.io/library/mongo". Use WithRawImage to opt out of this.
 func (r *ContainerRunner) WithImage(image string) *ContainerRunner {
 	r.image = image
 	if !substringContainedInSlice(image, RegistryExtensionOptions) {
@@ -93,18 +220,333 @@ func (r *ContainerRunner) WithImage(image string) *ContainerRunner {
 	return r
 }
 
+// WithRawImage sets the container image that should be used verbatim, without
+// the docker.io/library/ prefixing applied by WithImage. Use this when always
+// passing fully-qualified references.
+func (r *ContainerRunner) WithRawImage(image string) *ContainerRunner {
+	r.image = image
+	return r
+}
+
+// Image returns the image reference that will be pulled and run, after the
+// normalization applied by WithImage
+func (r *ContainerRunner) Image() string {
+	return r.image
+}
+
 // WithName sets the name of the container. Note that running Start with a
 // container name that already exists will cause Start to fail.
 func (r *ContainerRunner) WithName(name string) *ContainerRunner {
 	r.name = name
+	r.namePrefix = ""
 	if len(name) == 0 {
 		r.name = DefaultContainerName
 	}
 	return r
 }
 
+// WithNamePrefix names the container "<prefix>-<short random id>", which is
+// unique but easier to recognize in `docker ps` than a full UUID. A new name
+// is generated on every Start.
+func (r *ContainerRunner) WithNamePrefix(prefix string) *ContainerRunner {
+	r.namePrefix = prefix
+	return r
+}
+
+// Name returns the name of the container, which is only known after Start
+// when it is generated by WithNamePrefix
+func (r *ContainerRunner) Name() string {
+	return r.name
+}
+
+// WithEnvironmentVariable sets an environment variable in the container.
+// Variables set this way take precedence over the ones read from env files,
+// regardless of the order in which the builder methods are called.
 func (r *ContainerRunner) WithEnvironmentVariable(key, val string) *ContainerRunner {
-	r.env = append(r.env, fmt.Sprintf("%v=%v", key, val))
+	r.explicitEnv = append(r.explicitEnv, fmt.Sprintf("%v=%v", key, val))
+	r.env = mergeEnv(r.fileEnv, r.explicitEnv)
+	return r
+}
+
+// WithDockerSocket bind mounts the host's docker socket read-only into the
+// container at /var/run/docker.sock, which allows the container to manage
+// other containers. The host socket is resolved from DOCKER_HOST and falls
+// back to /var/run/docker.sock. DOCKER_HOST values that are not unix sockets
+// cannot be mounted and cause Start to fail.
+func (r *ContainerRunner) WithDockerSocket() *ContainerRunner {
+	socket := DefaultDockerSocket
+	if host := os.Getenv("DOCKER_HOST"); len(host) > 0 {
+		if !strings.HasPrefix(host, "unix://") {
+			r.setErr(fmt.Errorf("mounting docker socket %v: %w", host, ErrDockerHostNotUnix))
+			return r
+		}
+		socket = strings.TrimPrefix(host, "unix://")
+	}
+	r.binds = append(r.binds, fmt.Sprintf("%v:%v:ro", socket, DefaultDockerSocket))
+	return r
+}
+
+// WithKeepOnFailure keeps containers started with StartForTest around when
+// the test fails, see ContainerRunnerOpts.KeepOnFailure
+func (r *ContainerRunner) WithKeepOnFailure() *ContainerRunner {
+	r.opts.KeepOnFailure = true
+	return r
+}
+
+// WithSysctl sets a namespaced kernel parameter, such as net.core.somaxconn,
+// inside the container
+func (r *ContainerRunner) WithSysctl(key, val string) *ContainerRunner {
+	if len(key) == 0 {
+		r.setErr(errors.New("sysctl key must not be empty"))
+		return r
+	}
+	r.sysctls[key] = val
+	return r
+}
+
+// WithMacAddress pins the MAC address of the container's interface on its
+// default network, e.g. "02:42:ac:11:00:02". The default gateway cannot be
+// pinned per container; Docker assigns it from the network's IPAM config.
+func (r *ContainerRunner) WithMacAddress(mac string) *ContainerRunner {
+	hw, err := net.ParseMAC(mac)
+	if err != nil || len(hw) != 6 {
+		r.setErr(fmt.Errorf("invalid mac address %q", mac))
+		return r
+	}
+	r.macAddress = hw.String()
+	return r
+}
+
+// WithDNSOption adds an option to the container's resolv.conf, e.g.
+// "ndots:1" or "timeout:1". Docker's defaults are kept when none is added.
+func (r *ContainerRunner) WithDNSOption(opt string) *ContainerRunner {
+	if !containsString(r.dnsOptions, opt) {
+		r.dnsOptions = append(r.dnsOptions, opt)
+	}
+	return r
+}
+
+// WithHostGatewayAlias resolves name inside the container to the host, so the
+// container can call back into services running on it, e.g. name "host" makes
+// "http://host:8080" reach port 8080 on the host. This maps name to Docker's
+// host-gateway, which requires Docker 20.10 or newer; on older daemons use
+// HostGatewayIP instead.
+func (r *ContainerRunner) WithHostGatewayAlias(name string) *ContainerRunner {
+	if len(name) == 0 || strings.ContainsAny(name, ": ") {
+		r.setErr(fmt.Errorf("invalid host alias %q", name))
+		return r
+	}
+	host := name + ":host-gateway"
+	if !containsString(r.extraHosts, host) {
+		r.extraHosts = append(r.extraHosts, host)
+	}
+	return r
+}
+
+// WithMetadata stores arbitrary metadata, such as the test name or scenario,
+// on the runner. Metadata is kept in memory only and is never passed to
+// Docker.
+func (r *ContainerRunner) WithMetadata(key, val string) *ContainerRunner {
+	r.metadata[key] = val
+	return r
+}
+
+// Metadata returns a copy of the metadata stored with WithMetadata
+func (r *ContainerRunner) Metadata() map[string]string {
+	metadata := make(map[string]string, len(r.metadata))
+	for k, v := range r.metadata {
+		metadata[k] = v
+	}
+	return metadata
+}
+
+// WithRestartPolicy sets the policy Docker uses to restart the container when
+// it exits: "no", "always", "unless-stopped" or "on-failure". maxRetries
+// limits the number of restarts and is only valid with "on-failure".
+func (r *ContainerRunner) WithRestartPolicy(name string, maxRetries int) *ContainerRunner {
+	policy := container.RestartPolicy{Name: name, MaximumRetryCount: maxRetries}
+	switch {
+	case !policy.IsNone() && !policy.IsAlways() && !policy.IsUnlessStopped() && !policy.IsOnFailure():
+		r.setErr(fmt.Errorf("invalid restart policy %q", name))
+	case maxRetries < 0 || maxRetries > 0 && !policy.IsOnFailure():
+		r.setErr(fmt.Errorf("invalid maximum retry count %v for restart policy %q", maxRetries, name))
+	default:
+		r.restartPolicy = policy
+	}
+	return r
+}
+
+// WithOomScoreAdj tunes how likely the kernel is to kill the container when
+// the host runs out of memory, from -1000 (never) to 1000 (first)
+func (r *ContainerRunner) WithOomScoreAdj(n int) *ContainerRunner {
+	if n < -1000 || n > 1000 {
+		r.setErr(fmt.Errorf("oom score adjustment %v out of range -1000..1000", n))
+		return r
+	}
+	r.oomScoreAdj = n
+	return r
+}
+
+// WithMemorySwappiness tunes how aggressively the container's anonymous pages
+// are swapped out, from 0 (never) to 100 (freely)
+func (r *ContainerRunner) WithMemorySwappiness(n int64) *ContainerRunner {
+	if n < 0 || n > 100 {
+		r.setErr(fmt.Errorf("memory swappiness %v out of range 0..100", n))
+		return r
+	}
+	r.resources.MemorySwappiness = &n
+	return r
+}
+
+// WithMemoryLimit sets the hard memory limit of the container, in bytes. The
+// container is killed by the kernel when it uses more.
+func (r *ContainerRunner) WithMemoryLimit(bytes int64) *ContainerRunner {
+	if bytes <= 0 {
+		r.setErr(fmt.Errorf("memory limit %v must be positive", bytes))
+		return r
+	}
+	r.resources.Memory = bytes
+	return r
+}
+
+// WithMemoryReservation sets the soft memory limit of the container, in bytes.
+// The container may use more, but Docker reclaims memory down to the
+// reservation when the host is under memory pressure. It must not exceed the
+// hard limit set with WithMemoryLimit.
+func (r *ContainerRunner) WithMemoryReservation(bytes int64) *ContainerRunner {
+	if bytes <= 0 {
+		r.setErr(fmt.Errorf("memory reservation %v must be positive", bytes))
+		return r
+	}
+	r.resources.MemoryReservation = bytes
+	return r
+}
+
+// WithOOMKillDisable disables the kernel's OOM killer for the container, so
+// that it hangs instead of being killed when it exceeds its memory limit,
+// which leaves it around for inspection. It requires a memory limit set with
+// WithMemoryLimit; without one the host itself can run out of memory, and
+// Start logs a warning.
+func (r *ContainerRunner) WithOOMKillDisable(disable bool) *ContainerRunner {
+	r.resources.OomKillDisable = &disable
+	return r
+}
+
+// WithAttach runs the container attached, like `docker run` without -d: Start
+// streams the container's output to the writers set with WithStdout and
+// WithStderr and blocks until the container exits. A non-zero exit code is
+// returned as an *ExitError.
+func (r *ContainerRunner) WithAttach(attach bool) *ContainerRunner {
+	r.attach = attach
+	return r
+}
+
+// WithNoNetwork runs the container without any network access. Port bindings
+// are meaningless without a network and are skipped.
+func (r *ContainerRunner) WithNoNetwork(disabled bool) *ContainerRunner {
+	r.noNetwork = disabled
+	return r
+}
+
+// WithCgroupParent places the container under the given parent cgroup, for
+// environments that account or enforce quotas per cgroup
+func (r *ContainerRunner) WithCgroupParent(path string) *ContainerRunner {
+	r.resources.CgroupParent = path
+	return r
+}
+
+// WithPidMode sets the PID namespace of the container: "host" to share the
+// host's namespace, or "container:<name|id>" to join another container's
+func (r *ContainerRunner) WithPidMode(mode string) *ContainerRunner {
+	if mode != "host" && !isContainerMode(mode) {
+		r.setErr(fmt.Errorf("invalid pid mode %q", mode))
+		return r
+	}
+	r.pidMode = mode
+	return r
+}
+
+// WithIpcMode sets the IPC namespace of the container: "none", "private",
+// "shareable" to allow other containers to join it, "host" to share the
+// host's namespace, or "container:<name|id>" to join another container's
+func (r *ContainerRunner) WithIpcMode(mode string) *ContainerRunner {
+	switch {
+	case mode == "none", mode == "private", mode == "shareable", mode == "host":
+	case isContainerMode(mode):
+	default:
+		r.setErr(fmt.Errorf("invalid ipc mode %q", mode))
+		return r
+	}
+	r.ipcMode = mode
+	return r
+}
+
+// WithIsolation sets the isolation technology of a Windows container:
+// "default", "process" or "hyperv". Linux daemons only support the default
+// isolation, so this is a no-op there.
+func (r *ContainerRunner) WithIsolation(mode string) *ContainerRunner {
+	switch mode {
+	case "default", "process", "hyperv":
+		r.isolation = mode
+	default:
+		r.setErr(fmt.Errorf("invalid isolation mode %q", mode))
+	}
+	return r
+}
+
+// WithRuntime runs the container with the named OCI runtime, such as "runsc"
+// for gVisor or "kata-runtime", which must be configured on the daemon. Start
+// fails with ErrUnknownRuntime if it isn't. OCI annotations cannot be passed
+// by this version of the Docker API.
+func (r *ContainerRunner) WithRuntime(name string) *ContainerRunner {
+	r.runtime = name
+	return r
+}
+
+// WithDelayedStart makes Start wait for d before the container is created,
+// once the image is available, to simulate a service that joins late. The wait
+// is aborted when ctx is done.
+func (r *ContainerRunner) WithDelayedStart(d time.Duration) *ContainerRunner {
+	r.startDelay = d
+	return r
+}
+
+// WithForceRecreate removes any existing container with the same name before
+// the container is created in Start, instead of failing on the name conflict.
+func (r *ContainerRunner) WithForceRecreate() *ContainerRunner {
+	r.forceRecreate = true
+	return r
+}
+
+// WithLogger sets the logger that receives the runner's log messages. It
+// defaults to the standard logrus logger.
+func (r *ContainerRunner) WithLogger(logger log.FieldLogger) *ContainerRunner {
+	r.logger = logger
+	if len(r.logFields) > 0 {
+		r.logger = logger.WithFields(r.logFields)
+	}
+	return r
+}
+
+// WithLogFields attaches structured fields, such as a run id or the container
+// name, to every message the runner logs. This keeps the output of many
+// containers apart. Fields are kept when the logger is replaced with
+// WithLogger.
+func (r *ContainerRunner) WithLogFields(fields map[string]interface{}) *ContainerRunner {
+	if r.logFields == nil {
+		r.logFields = log.Fields{}
+	}
+	for k, v := range fields {
+		r.logFields[k] = v
+	}
+	r.logger = r.logger.WithFields(fields)
+	return r
+}
+
+// WithOutput sets the writer that receives the human-readable progress of
+// streaming operations such as image pulls. Progress is discarded by default.
+func (r *ContainerRunner) WithOutput(w io.Writer) *ContainerRunner {
+	r.output = w
 	return r
 }
 
@@ -114,68 +556,375 @@ func (r *ContainerRunner) WithOptions(opts *ContainerRunnerOpts) *ContainerRunne
 	return r
 }
 
+// setErr records err as the builder error unless one was already recorded
+func (r *ContainerRunner) setErr(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
 // Start starts the container with the provided options
 func (e *ContainerRunner) Start(ctx context.Context) error {
-	var err error
-	e.client, err = client.NewEnvClient()
+	if e.err != nil {
+		return fmt.Errorf("invalid runner configuration: %w", e.err)
+	}
+	if e.noNetwork && len(e.networks) > 0 {
+		return errors.New("invalid runner configuration: networks cannot be attached when the network is disabled")
+	}
+	if e.resources.OomKillDisable != nil && *e.resources.OomKillDisable && e.resources.Memory == 0 {
+		e.logger.Warnln("disabling the OOM killer without a memory limit can exhaust the memory of the host")
+	}
+	if len(e.buildArgs) > 0 && e.build == nil {
+		return errors.New("invalid runner configuration: build args require WithBuild")
+	}
+	if e.resources.Memory > 0 && e.resources.MemoryReservation > e.resources.Memory {
+		return fmt.Errorf("invalid runner configuration: memory reservation %v exceeds memory limit %v", e.resources.MemoryReservation, e.resources.Memory)
+	}
+
+	err := e.Ping(ctx)
 	if err != nil {
-		return fmt.Errorf("creating env client: %w", err)
+		return err
 	}
 
-	log.Infoln("pulling image")
-	_, err = e.client.ImagePull(ctx, e.image, types.ImagePullOptions{})
+	err = e.ensureImage(ctx)
 	if err != nil {
-		return fmt.Errorf("pulling image: %w", err)
+		return err
 	}
 
-	log.Infoln("creating container")
-	resp, err := e.client.ContainerCreate(ctx, &container.Config{
-		Image:        e.image,
-		ExposedPorts: e.exposedPorts,
-		Env: e.env,
-	}, &container.HostConfig{
-		PortBindings: e.portBindings,
-	}, nil, e.name)
+	if err := e.resolveImageCmd(ctx); err != nil {
+		return err
+	}
+
+	if len(e.runtime) > 0 {
+		if err := e.checkRuntime(ctx); err != nil {
+			return err
+		}
+	}
+
+	if e.startDelay > 0 {
+		e.logger.Infof("delaying start by %v", e.startDelay)
+		select {
+		case <-time.After(e.startDelay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if len(e.namePrefix) > 0 {
+		e.name = fmt.Sprintf("%v-%v", e.namePrefix, strings.SplitN(uuid.New().String(), "-", 2)[0])
+	}
+
+	if err := e.resolveEnvTemplates(); err != nil {
+		return err
+	}
+
+	if e.forceRecreate && len(e.name) > 0 {
+		if err := e.removeExisting(ctx); err != nil {
+			return err
+		}
+	}
+
+	e.logger.WithFields(log.Fields{
+		"image": e.image,
+		"env":   e.redactedEnv(),
+	}).Debugln("container configuration")
+	e.logger.Infoln("creating container")
+	resp, err := e.client.ContainerCreate(ctx, e.containerConfig(), e.hostConfig(), e.networkingConfig(), e.name)
 	if err != nil {
-		return fmt.Errorf("creating container: %w", err)
+		return fmt.Errorf("creating container: %w", classifyError(err))
 	}
 
 	// Save the container id
 	e.id = resp.ID
+	e.removed = false
+	e.trackLeak()
+	if err := e.checkWarnings(resp.Warnings); err != nil {
+		if removeErr := e.forceRemove(ctx, err); removeErr != nil {
+			e.logger.Warnln(removeErr)
+		}
+		return err
+	}
+	e.readiness = ReadinessResult{}
+
+	if err := e.connectNetworks(ctx); err != nil {
+		return err
+	}
 
-	log.Infoln("starting container")
+	if e.attach {
+		return e.startAttached(ctx)
+	}
+
+	e.logger.Infoln("starting container")
 	if err := e.client.ContainerStart(ctx, e.id, types.ContainerStartOptions{}); err != nil {
-		return fmt.Errorf("starting container: %w", err)
+		return fmt.Errorf("starting container: %w", classifyError(err))
+	}
+	e.logger.Infoln("container started")
+	if e.stdout != nil || e.stderr != nil {
+		if err := e.streamLogs(); err != nil {
+			return err
+		}
+	}
+	e.readiness, err = e.WaitReady(ctx, e.waitStrategies...)
+	return err
+}
+
+// connect creates the docker client from the environment unless one exists
+func (e *ContainerRunner) connect() error {
+	if e.client != nil {
+		return nil
+	}
+	c, err := newEnvClient(e.clientTimeout)
+	if err != nil {
+		return fmt.Errorf("creating env client: %w", err)
+	}
+	e.client = c
+	return nil
+}
+
+// Ping checks that the docker daemon is reachable, returning
+// ErrDaemonUnreachable if it isn't. Start pings the daemon before doing
+// anything else.
+func (e *ContainerRunner) Ping(ctx context.Context) error {
+	err := e.connect()
+	if err != nil {
+		return err
+	}
+	if _, err := e.client.Ping(ctx); err != nil {
+		return fmt.Errorf("%w (is docker running?): %v", ErrDaemonUnreachable, err)
 	}
-	log.Infoln("container started")
 	return nil
 }
 
-// Stop stops the container that was started using Start
-func (e *ContainerRunner) Stop(ctx context.Context) error {
-	log.Infoln("stopping container")
+// checkRuntime returns ErrUnknownRuntime unless the daemon has the runtime
+func (e *ContainerRunner) checkRuntime(ctx context.Context) error {
+	info, err := e.client.Info(ctx)
+	if err != nil {
+		return fmt.Errorf("getting daemon info: %w", err)
+	}
+	if _, ok := info.Runtimes[e.runtime]; !ok {
+		return fmt.Errorf("runtime %v: %w", e.runtime, ErrUnknownRuntime)
+	}
+	return nil
+}
+
+// removeExisting force removes the container that has the runner's name, if
+// any, and waits until it is gone
+func (e *ContainerRunner) removeExisting(ctx context.Context) error {
+	err := e.client.ContainerRemove(ctx, e.name, types.ContainerRemoveOptions{
+		RemoveVolumes: e.opts.RemoveVolumesOnFinalization,
+		Force:         true,
+	})
+	if client.IsErrNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("removing existing container: %w", err)
+	}
+	e.logger.Infoln("removed existing container")
+	return e.waitForRemoval(ctx, e.name, DefaultRemovalTimeout)
+}
+
+// displayProgress decodes the JSON progress stream of a streaming operation
+// into the configured output and closes it. Errors reported in the stream are
+// returned. The stream is closed as soon as ctx is done, so that a slow
+// operation such as a large pull can be cancelled promptly, in which case
+// ctx.Err() is returned.
+func (e *ContainerRunner) displayProgress(ctx context.Context, stream io.ReadCloser) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			stream.Close()
+		case <-done:
+		}
+	}()
+	defer stream.Close()
+
+	err := jsonmessage.DisplayJSONMessagesStream(stream, e.output, 0, false, nil)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// containerConfig builds the portable configuration of the container
+func (e *ContainerRunner) containerConfig() *container.Config {
+	exposedPorts := e.exposedPorts
+	if e.noNetwork {
+		exposedPorts = nil
+	}
+	return &container.Config{
+		Image:        e.image,
+		ExposedPorts: exposedPorts,
+		Env:          e.containerEnv(),
+		WorkingDir:   e.workdir,
+		Cmd:          e.command(),
+		Entrypoint:   e.entrypoint,
+		MacAddress:   e.macAddress,
+		AttachStdout: e.attach,
+		AttachStderr: e.attach,
+		Labels:       e.containerLabels(),
+		Healthcheck:  e.healthcheck,
+		StopSignal:   e.stopSignal,
+	}
+}
+
+// hostConfig builds the host specific configuration of the container
+func (e *ContainerRunner) hostConfig() *container.HostConfig {
+	config := &container.HostConfig{
+		PortBindings:    e.portBindings,
+		Binds:           e.binds,
+		Mounts:          e.mounts,
+		Sysctls:         e.sysctls,
+		DNSOptions:      e.dnsOptions,
+		ExtraHosts:      e.extraHosts,
+		RestartPolicy:   e.restartPolicy,
+		LogConfig:       e.logConfig,
+		OomScoreAdj:     e.oomScoreAdj,
+		Resources:       e.resources,
+		PidMode:         container.PidMode(e.pidMode),
+		IpcMode:         container.IpcMode(e.ipcMode),
+		Runtime:         e.runtime,
+		Isolation:       container.Isolation(e.isolation),
+		PublishAllPorts: e.publishAll,
+	}
+	if len(e.networks) > 0 {
+		config.NetworkMode = container.NetworkMode(e.networks[0])
+	}
+	if e.noNetwork {
+		config.NetworkMode = "none"
+		config.PortBindings = nil
+		config.PublishAllPorts = false
+	}
+	return config
+}
+
+// Stop stops the container that was started using Start. By default the
+// container is removed and force removed according to the runner's options,
+// which can be overridden for this call using opts. Once the container was
+// removed, further calls to Stop do nothing and return nil.
+func (e *ContainerRunner) Stop(ctx context.Context, opts ...StopOption) error {
+	cfg := e.stopConfig()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return e.stop(ctx, cfg)
+}
+
+// stop stops the container and removes it according to cfg
+func (e *ContainerRunner) stop(ctx context.Context, cfg stopConfig) error {
+	// Stopping a container that was already removed is a no-op, so that
+	// Stop can be both deferred and called explicitly
+	if e.removed {
+		return nil
+	}
+	e.logger.Infoln("stopping container")
 	// If we don't have a container id
 	if len(e.id) == 0 {
 		return ErrNoContainerId
 	}
 
-	timeout := time.Minute
-	err := e.client.ContainerStop(ctx, e.id, &timeout)
+	timeout := cfg.timeout
+	err := e.containerStop(ctx, &timeout, cfg.deadline)
 	if err != nil {
-		return fmt.Errorf("stopping container: %w", err)
+		if !cfg.remove || !cfg.force {
+			return fmt.Errorf("stopping container: %w", err)
+		}
+		e.logger.Warnf("stopping container failed, falling back to force removal: %v", err)
+		return e.forceRemove(ctx, err)
+	}
+	e.logger.Infoln("container stopped")
+	e.untrackLeak()
+	if cfg.remove {
+		return e.remove(ctx, cfg.force)
+	}
+	return nil
+}
+
+// containerStop asks the daemon to stop the container, giving up with
+// ErrStopTimeout after deadline unless it is zero
+func (e *ContainerRunner) containerStop(ctx context.Context, timeout *time.Duration, deadline time.Duration) error {
+	if deadline <= 0 {
+		return e.client.ContainerStop(ctx, e.id, timeout)
+	}
+	stopCtx, cancel := context.WithTimeout(ctx, deadline)
+	defer cancel()
+	err := e.client.ContainerStop(stopCtx, e.id, timeout)
+	if err != nil && ctx.Err() == nil && errors.Is(stopCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %v: %v", ErrStopTimeout, deadline, err)
+	}
+	return err
+}
+
+// remove removes the stopped container, falling back to force removal if
+// force is set
+func (e *ContainerRunner) remove(ctx context.Context, force bool) error {
+	e.logger.Infoln("removing container")
+	backoff := removeBackoff
+	err := e.client.ContainerRemove(ctx, e.id, types.ContainerRemoveOptions{
+		RemoveVolumes: e.opts.RemoveVolumesOnFinalization,
+	})
+	// Overlay filesystems intermittently report the container's mounts as
+	// busy right after it stopped
+	for attempt := 1; e.isRetryable(err, isResourceBusy) && attempt < DefaultRemoveAttempts; attempt++ {
+		e.logger.Warnf("removing container failed (attempt %v/%v), retrying in %v: %v", attempt, DefaultRemoveAttempts, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return fmt.Errorf("removing container: %w", ctx.Err())
+		}
+		backoff *= 2
+		err = e.client.ContainerRemove(ctx, e.id, types.ContainerRemoveOptions{
+			RemoveVolumes: e.opts.RemoveVolumesOnFinalization,
+		})
 	}
-	log.Infoln("container stopped")
-	if e.opts.RemoveOnFinalization {
-		log.Infoln("removing container")
-		err = e.client.ContainerRemove(ctx, e.id, types.ContainerRemoveOptions{})
-		if err != nil {
+	if err != nil {
+		if !force {
 			return fmt.Errorf("removing container: %w", err)
 		}
-		log.Infoln("container removed")
+		e.logger.Warnf("removing container failed, falling back to force removal: %v", err)
+		return e.forceRemove(ctx, err)
+	}
+	e.logger.Infoln("container removed")
+	e.markRemoved()
+	return nil
+}
+
+// forceRemove removes the container with Force set after a graceful stop or
+// removal failed with cause
+func (e *ContainerRunner) forceRemove(ctx context.Context, cause error) error {
+	err := e.client.ContainerRemove(ctx, e.id, types.ContainerRemoveOptions{
+		RemoveVolumes: e.opts.RemoveVolumesOnFinalization,
+		Force:         true,
+	})
+	if err != nil {
+		return fmt.Errorf("force removing container after %v: %w", cause, err)
 	}
+	e.logger.Infoln("container force removed")
+	e.markRemoved()
 	return nil
 }
 
+// markRemoved forgets the id of the removed container
+func (e *ContainerRunner) markRemoved() {
+	e.untrackLeak()
+	e.id = ""
+	e.removed = true
+}
+
+// isResourceBusy returns true if err reports that a mount of the container
+// is still busy
+func isResourceBusy(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "device or resource busy")
+}
+
+// isContainerMode returns true if mode joins the namespace of another
+// container, i.e. has the form "container:<name|id>"
+func isContainerMode(mode string) bool {
+	return strings.HasPrefix(mode, "container:") && len(mode) > len("container:")
+}
+
 // substringContainedInSlice returns true if the substr can be found as a substring
 // of any member of slice
 func substringContainedInSlice(str string, substrs []string) bool {
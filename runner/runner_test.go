@@ -2,7 +2,11 @@ package runner
 
 import (
 	"context"
+	"errors"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
 	"github.com/stretchr/testify/require"
+	"os"
 	"testing"
 	"time"
 )
@@ -60,3 +64,354 @@ func TestSubStringInStrings(t *testing.T) {
 		})
 	}
 }
+
+func TestWithNoNetwork(t *testing.T) {
+	runner := NewContainerRunner().
+		WithImage("busybox").
+		WithPorts(8080).
+		WithNoNetwork(true)
+
+	hostConfig := runner.hostConfig()
+	require.Equal(t, "none", string(hostConfig.NetworkMode))
+	require.Empty(t, hostConfig.PortBindings)
+	require.Empty(t, runner.containerConfig().ExposedPorts)
+}
+
+func TestClone(t *testing.T) {
+	base := NewContainerRunner().
+		WithImage("postgres").
+		WithEnvironmentVariable("POSTGRES_USER", "postgres").
+		WithPorts(5432)
+	base.id = "id"
+
+	clone := base.Clone().
+		WithEnvironmentVariable("POSTGRES_DB", "test").
+		WithPorts(5433).
+		WithMetadata("scenario", "clone")
+	clone.opts.RemoveOnFinalization = false
+
+	require.Empty(t, clone.id)
+	require.Nil(t, clone.client)
+	require.Equal(t, []string{"POSTGRES_USER=postgres"}, base.env)
+	require.Equal(t, []string{"POSTGRES_USER=postgres", "POSTGRES_DB=test"}, clone.env)
+	require.Len(t, base.exposedPorts, 1)
+	require.Len(t, clone.exposedPorts, 2)
+	require.Empty(t, base.Metadata())
+	require.True(t, base.opts.RemoveOnFinalization)
+}
+
+func TestWithMemoryReservation(t *testing.T) {
+	runner := NewContainerRunner().
+		WithImage("redis").
+		WithMemoryLimit(512 << 20).
+		WithMemoryReservation(256 << 20)
+	resources := runner.hostConfig().Resources
+	require.Equal(t, int64(512<<20), resources.Memory)
+	require.Equal(t, int64(256<<20), resources.MemoryReservation)
+
+	runner = NewContainerRunner().
+		WithImage("redis").
+		WithMemoryReservation(1 << 30).
+		WithMemoryLimit(512 << 20)
+	runner.client = &mockClient{}
+	err := runner.Start(context.Background())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "exceeds memory limit")
+}
+
+func TestWithNamePrefix(t *testing.T) {
+	var names []string
+	runner := NewContainerRunner().
+		WithImage("redis").
+		WithNamePrefix("cache")
+	runner.client = &mockClient{
+		containerCreate: func(name string) (container.ContainerCreateCreatedBody, error) {
+			names = append(names, name)
+			return container.ContainerCreateCreatedBody{ID: name}, nil
+		},
+	}
+
+	require.NoError(t, runner.Start(context.Background()))
+	require.NoError(t, runner.Stop(context.Background()))
+	require.NoError(t, runner.Start(context.Background()))
+
+	require.Len(t, names, 2)
+	require.Regexp(t, `^cache-[0-9a-f]{8}$`, names[0])
+	require.NotEqual(t, names[0], names[1])
+	require.Equal(t, names[1], runner.Name())
+}
+
+func TestReset(t *testing.T) {
+	runner := NewContainerRunner().
+		WithImage("redis").
+		WithOptions(&ContainerRunnerOpts{RemoveOnFinalization: false})
+	runner.client = &mockClient{}
+	require.NoError(t, runner.Start(context.Background()))
+	require.NoError(t, runner.Stop(context.Background()))
+	require.Equal(t, "id", runner.id)
+
+	runner.Reset()
+	require.Empty(t, runner.id)
+	require.False(t, runner.ImageWasPulled())
+	require.Equal(t, "docker.io/library/redis", runner.Image())
+	require.NoError(t, runner.Start(context.Background()))
+}
+
+func TestWithDelayedStart(t *testing.T) {
+	runner := NewContainerRunner().
+		WithImage("redis").
+		WithDelayedStart(50 * time.Millisecond)
+	runner.client = &mockClient{}
+	started := time.Now()
+	require.NoError(t, runner.Start(context.Background()))
+	require.True(t, time.Since(started) >= 50*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	runner = NewContainerRunner().
+		WithImage("redis").
+		WithDelayedStart(time.Hour)
+	runner.client = &mockClient{}
+	require.Equal(t, context.Canceled, runner.Start(ctx))
+}
+
+func TestWithDockerSocket(t *testing.T) {
+	if host, ok := os.LookupEnv("DOCKER_HOST"); ok {
+		defer os.Setenv("DOCKER_HOST", host)
+	} else {
+		defer os.Unsetenv("DOCKER_HOST")
+	}
+	for _, tc := range []struct {
+		name string
+		host string
+		bind string
+		err  error
+	}{
+		{name: "unset", bind: "/var/run/docker.sock:/var/run/docker.sock:ro"},
+		{name: "unix", host: "unix:///run/user/1000/docker.sock", bind: "/run/user/1000/docker.sock:/var/run/docker.sock:ro"},
+		{name: "tcp", host: "tcp://127.0.0.1:2375", err: ErrDockerHostNotUnix},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if len(tc.host) > 0 {
+				require.NoError(t, os.Setenv("DOCKER_HOST", tc.host))
+			} else {
+				require.NoError(t, os.Unsetenv("DOCKER_HOST"))
+			}
+			runner := NewContainerRunner().WithImage("docker").WithDockerSocket()
+			if tc.err != nil {
+				require.True(t, errors.Is(runner.err, tc.err))
+				require.Empty(t, runner.hostConfig().Binds)
+				return
+			}
+			require.NoError(t, runner.err)
+			require.Equal(t, []string{tc.bind}, runner.hostConfig().Binds)
+		})
+	}
+}
+
+func TestWithRuntime(t *testing.T) {
+	for _, tc := range []struct {
+		runtime string
+		err     error
+	}{
+		{runtime: "runsc"},
+		{runtime: "kata", err: ErrUnknownRuntime},
+	} {
+		t.Run(tc.runtime, func(t *testing.T) {
+			created := false
+			runner := NewContainerRunner().WithImage("redis").WithRuntime(tc.runtime)
+			runner.client = &mockClient{
+				info: func() (types.Info, error) {
+					return types.Info{Runtimes: map[string]types.Runtime{
+						"runc":  {Path: "runc"},
+						"runsc": {Path: "/usr/local/bin/runsc"},
+					}}, nil
+				},
+				containerCreate: func(name string) (container.ContainerCreateCreatedBody, error) {
+					created = true
+					return container.ContainerCreateCreatedBody{ID: "id"}, nil
+				},
+			}
+			err := runner.Start(context.Background())
+			if tc.err != nil {
+				require.True(t, errors.Is(err, tc.err))
+				require.False(t, created)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, created)
+			require.Equal(t, tc.runtime, runner.hostConfig().Runtime)
+		})
+	}
+}
+
+func TestWithRestartPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		maxRetries int
+		valid      bool
+	}{
+		{"no", 0, true},
+		{"always", 0, true},
+		{"unless-stopped", 0, true},
+		{"on-failure", 0, true},
+		{"on-failure", 3, true},
+		{"on-failure", -1, false},
+		{"always", 3, false},
+		{"unless-stopped", 1, false},
+		{"sometimes", 0, false},
+	} {
+		runner := NewContainerRunner().WithImage("redis").WithRestartPolicy(tc.name, tc.maxRetries)
+		if !tc.valid {
+			require.Error(t, runner.err, "%v %v", tc.name, tc.maxRetries)
+			require.Empty(t, runner.hostConfig().RestartPolicy.Name)
+			continue
+		}
+		require.NoError(t, runner.err, "%v %v", tc.name, tc.maxRetries)
+		require.Equal(t, container.RestartPolicy{Name: tc.name, MaximumRetryCount: tc.maxRetries}, runner.hostConfig().RestartPolicy)
+	}
+}
+
+func TestWithPidModeAndIpcMode(t *testing.T) {
+	for _, tc := range []struct {
+		mode string
+		pid  bool
+		ipc  bool
+	}{
+		{"host", true, true},
+		{"container:db", true, true},
+		{"container:", false, false},
+		{"none", false, true},
+		{"private", false, true},
+		{"shareable", false, true},
+		{"", false, false},
+		{"hosts", false, false},
+	} {
+		runner := NewContainerRunner().WithImage("redis").WithPidMode(tc.mode)
+		if tc.pid {
+			require.NoError(t, runner.err, tc.mode)
+			require.Equal(t, tc.mode, string(runner.hostConfig().PidMode))
+		} else {
+			require.Error(t, runner.err, tc.mode)
+		}
+
+		runner = NewContainerRunner().WithImage("redis").WithIpcMode(tc.mode)
+		if tc.ipc {
+			require.NoError(t, runner.err, tc.mode)
+			require.Equal(t, tc.mode, string(runner.hostConfig().IpcMode))
+		} else {
+			require.Error(t, runner.err, tc.mode)
+		}
+	}
+}
+
+func TestWithOomScoreAdjAndMemorySwappiness(t *testing.T) {
+	for _, tc := range []struct {
+		n     int
+		valid bool
+	}{
+		{-1001, false},
+		{-1000, true},
+		{0, true},
+		{1000, true},
+		{1001, false},
+	} {
+		runner := NewContainerRunner().WithImage("redis").WithOomScoreAdj(tc.n)
+		if !tc.valid {
+			require.Error(t, runner.err, tc.n)
+			continue
+		}
+		require.NoError(t, runner.err, tc.n)
+		require.Equal(t, tc.n, runner.hostConfig().OomScoreAdj)
+	}
+
+	for _, tc := range []struct {
+		n     int64
+		valid bool
+	}{
+		{-1, false},
+		{0, true},
+		{100, true},
+		{101, false},
+	} {
+		runner := NewContainerRunner().WithImage("redis").WithMemorySwappiness(tc.n)
+		if !tc.valid {
+			require.Error(t, runner.err, tc.n)
+			require.Nil(t, runner.hostConfig().MemorySwappiness)
+			continue
+		}
+		require.NoError(t, runner.err, tc.n)
+		require.Equal(t, tc.n, *runner.hostConfig().MemorySwappiness)
+	}
+}
+
+func TestWithSysctl(t *testing.T) {
+	runner := NewContainerRunner().
+		WithImage("redis").
+		WithSysctl("net.core.somaxconn", "1024").
+		WithSysctl("net.ipv4.tcp_syncookies", "0").
+		WithSysctl("net.core.somaxconn", "4096")
+	require.NoError(t, runner.err)
+	require.Equal(t, map[string]string{
+		"net.core.somaxconn":      "4096",
+		"net.ipv4.tcp_syncookies": "0",
+	}, runner.hostConfig().Sysctls)
+
+	runner = NewContainerRunner().WithImage("redis").WithSysctl("", "1")
+	require.Error(t, runner.err)
+	require.Empty(t, runner.hostConfig().Sysctls)
+}
+
+func TestWithMacAddress(t *testing.T) {
+	for _, tc := range []struct {
+		mac   string
+		want  string
+		valid bool
+	}{
+		{"02:42:ac:11:00:02", "02:42:ac:11:00:02", true},
+		{"02-42-AC-11-00-02", "02:42:ac:11:00:02", true},
+		{"02:42:ac:11:00", "", false},
+		{"00:00:00:00:fe:80:00:00:00:00:00:00:02:00:5e:10:00:00:00:01", "", false},
+	} {
+		runner := NewContainerRunner().WithImage("redis").WithMacAddress(tc.mac)
+		if !tc.valid {
+			require.Error(t, runner.err, tc.mac)
+			require.Empty(t, runner.containerConfig().MacAddress)
+			continue
+		}
+		require.NoError(t, runner.err, tc.mac)
+		require.Equal(t, tc.want, runner.containerConfig().MacAddress)
+	}
+}
+
+func TestWithIsolation(t *testing.T) {
+	for _, mode := range []string{"default", "process", "hyperv"} {
+		runner := NewContainerRunner().WithImage("nanoserver").WithIsolation(mode)
+		require.NoError(t, runner.err, mode)
+		require.Equal(t, container.Isolation(mode), runner.hostConfig().Isolation)
+	}
+
+	runner := NewContainerRunner().WithImage("nanoserver").WithIsolation("vm")
+	require.Error(t, runner.err)
+	require.Empty(t, runner.hostConfig().Isolation)
+}
+
+func TestWithDNSOption(t *testing.T) {
+	runner := NewContainerRunner().
+		WithImage("redis").
+		WithDNSOption("ndots:1").
+		WithDNSOption("timeout:1").
+		WithDNSOption("ndots:1")
+	require.Equal(t, []string{"ndots:1", "timeout:1"}, runner.hostConfig().DNSOptions)
+}
+
+func TestWithOOMKillDisable(t *testing.T) {
+	require.Nil(t, NewContainerRunner().WithImage("redis").hostConfig().Resources.OomKillDisable)
+
+	for _, disable := range []bool{true, false} {
+		runner := NewContainerRunner().WithImage("redis").WithOOMKillDisable(disable)
+		oomKillDisable := runner.hostConfig().Resources.OomKillDisable
+		require.NotNil(t, oomKillDisable)
+		require.Equal(t, disable, *oomKillDisable)
+	}
+}
@@ -0,0 +1,39 @@
+// Package runnertest provides testing helpers for containers started with the
+// runner package. It is kept separate so that the runner package does not
+// import testing.
+package runnertest
+
+import (
+	"context"
+	"errors"
+	"github.com/clarkmcc/container/runner"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// AssertLogContains fails the test unless a line of the container's logs
+// contains substring within timeout. Lines logged before the call count.
+func AssertLogContains(ctx context.Context, t testing.TB, r *runner.ContainerRunner, substring string, timeout time.Duration) {
+	t.Helper()
+	_, err := r.WaitForLogMatch(ctx, regexp.MustCompile(regexp.QuoteMeta(substring)), timeout)
+	if err != nil {
+		t.Fatalf("expected container logs to contain %q: %v", substring, err)
+	}
+}
+
+// AssertLogNotContains fails the test if a line of the container's logs
+// contains substring within timeout. Lines logged before the call count. The
+// assertion passes once timeout elapsed or the container exited without the
+// substring appearing.
+func AssertLogNotContains(ctx context.Context, t testing.TB, r *runner.ContainerRunner, substring string, timeout time.Duration) {
+	t.Helper()
+	line, err := r.WaitForLogMatch(ctx, regexp.MustCompile(regexp.QuoteMeta(substring)), timeout)
+	switch {
+	case err == nil:
+		t.Fatalf("expected container logs not to contain %q, found %q", substring, line)
+	case errors.Is(err, runner.ErrWaitTimeout), errors.Is(err, runner.ErrLogsEnded):
+	default:
+		t.Fatalf("reading container logs: %v", err)
+	}
+}
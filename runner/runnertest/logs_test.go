@@ -0,0 +1,95 @@
+package runnertest
+
+import (
+	"context"
+	"fmt"
+	"github.com/clarkmcc/container/runner"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTB records the failures of an assertion instead of failing the test
+type fakeTB struct {
+	testing.TB
+	failures []string
+}
+
+func (t *fakeTB) Helper() {}
+
+func (t *fakeTB) Fatalf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+// startWithLogs starts a container on a fake daemon whose logs are lines.
+// The log stream stays open for open before the daemon ends it.
+func startWithLogs(t *testing.T, open time.Duration, lines ...string) *runner.ContainerRunner {
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		switch {
+		case strings.HasSuffix(path, "/containers/create"):
+			fmt.Fprint(w, `{"Id": "id"}`)
+		case strings.HasSuffix(path, "/containers/id/json"):
+			fmt.Fprint(w, `{"Id": "id", "State": {"Running": true}}`)
+		case strings.HasSuffix(path, "/containers/id/logs"):
+			for _, line := range lines {
+				stdcopy.NewStdWriter(w, stdcopy.Stdout).Write([]byte(line + "\n"))
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-time.After(open):
+			case <-r.Context().Done():
+			}
+		case strings.HasSuffix(path, "/_ping"), strings.HasSuffix(path, "/images/create"),
+			strings.HasSuffix(path, "/containers/id/start"), strings.HasSuffix(path, "/containers/id/stop"),
+			strings.HasSuffix(path, "/containers/id"):
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, path)
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	t.Cleanup(daemon.Close)
+
+	host := os.Getenv("DOCKER_HOST")
+	t.Cleanup(func() { os.Setenv("DOCKER_HOST", host) })
+	os.Setenv("DOCKER_HOST", "tcp://"+strings.TrimPrefix(daemon.URL, "http://"))
+
+	r := runner.NewContainerRunner().WithImage("app")
+	r.StartForTest(t)
+	return r
+}
+
+func TestAssertLogContains(t *testing.T) {
+	r := startWithLogs(t, time.Minute, "starting", "listening on :8080")
+	ft := &fakeTB{}
+	AssertLogContains(context.Background(), ft, r, "listening on", time.Second)
+	require.Empty(t, ft.failures)
+
+	AssertLogContains(context.Background(), ft, r, "ready", 50*time.Millisecond)
+	require.Len(t, ft.failures, 1)
+	require.Contains(t, ft.failures[0], `"ready"`)
+}
+
+func TestAssertLogNotContains(t *testing.T) {
+	r := startWithLogs(t, 0, "starting", "panic: nil map")
+	ft := &fakeTB{}
+	// The logs end without the substring
+	AssertLogNotContains(context.Background(), ft, r, "deprecated", time.Second)
+	require.Empty(t, ft.failures)
+
+	AssertLogNotContains(context.Background(), ft, r, "panic:", time.Second)
+	require.Len(t, ft.failures, 1)
+	require.Contains(t, ft.failures[0], "panic: nil map")
+}
+
+func TestAssertLogNotContainsTimeout(t *testing.T) {
+	r := startWithLogs(t, time.Minute, "starting")
+	ft := &fakeTB{}
+	AssertLogNotContains(context.Background(), ft, r, "panic:", 50*time.Millisecond)
+	require.Empty(t, ft.failures)
+}
@@ -0,0 +1,47 @@
+package runner
+
+import (
+	"strings"
+)
+
+// redacted replaces the values of sensitive environment variables in logs
+const redacted = "<redacted>"
+
+// SecretEnvPatterns are the fragments of environment variable names, matched
+// case-insensitively, whose values are never logged
+var SecretEnvPatterns = []string{"PASSWORD", "SECRET", "TOKEN", "KEY"}
+
+// WithSecretEnv sets an environment variable like WithEnvironmentVariable,
+// but marks it as sensitive so that its value is never logged, even if its
+// name does not match SecretEnvPatterns
+func (r *ContainerRunner) WithSecretEnv(key, val string) *ContainerRunner {
+	if r.secretEnv == nil {
+		r.secretEnv = map[string]struct{}{}
+	}
+	r.secretEnv[key] = struct{}{}
+	return r.WithEnvironmentVariable(key, val)
+}
+
+// isSecretEnv returns whether the value of the environment variable key must
+// not be logged
+func (e *ContainerRunner) isSecretEnv(key string) bool {
+	if _, ok := e.secretEnv[key]; ok {
+		return true
+	}
+	return substringContainedInSlice(strings.ToUpper(key), SecretEnvPatterns)
+}
+
+// redactedEnv returns the container's environment for logging, with the
+// values of sensitive variables replaced
+func (e *ContainerRunner) redactedEnv() []string {
+	containerEnv := e.containerEnv()
+	env := make([]string, len(containerEnv))
+	for i, kv := range containerEnv {
+		key := strings.SplitN(kv, "=", 2)[0]
+		if e.isSecretEnv(key) {
+			kv = key + "=" + redacted
+		}
+		env[i] = kv
+	}
+	return env
+}
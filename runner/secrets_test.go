@@ -0,0 +1,30 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSecretEnvRedacted(t *testing.T) {
+	var out bytes.Buffer
+	logger := log.New()
+	logger.Out = &out
+	logger.Level = log.DebugLevel
+
+	runner := NewContainerRunner().
+		WithImage("postgres").
+		WithLogger(logger).
+		WithEnvironmentVariable("POSTGRES_PASSWORD", "hunter2").
+		WithEnvironmentVariable("POSTGRES_DB", "test").
+		WithSecretEnv("DSN", "postgres://user:letmein@db")
+	runner.client = &mockClient{}
+	require.NoError(t, runner.Start(context.Background()))
+
+	require.Equal(t, []string{"POSTGRES_PASSWORD=<redacted>", "POSTGRES_DB=test", "DSN=<redacted>"}, runner.redactedEnv())
+	require.Contains(t, out.String(), "POSTGRES_DB=test")
+	require.NotContains(t, out.String(), "hunter2")
+	require.NotContains(t, out.String(), "letmein")
+}
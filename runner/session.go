@@ -0,0 +1,109 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"github.com/google/uuid"
+	"strings"
+)
+
+// LabelSession records the id of the Session that created a container or
+// network
+const LabelSession = "com.clarkmcc.container/session"
+
+// Session is a namespace for the containers and networks of one test binary,
+// or of one test, so that runs in parallel don't collide on names and can be
+// torn down together. Runners and networks created from a session carry its
+// LabelSession label and are named after its prefix.
+type Session struct {
+	id     string
+	prefix string
+}
+
+// NewSession creates a session with a new unique id
+func NewSession() *Session {
+	id := strings.SplitN(uuid.New().String(), "-", 2)[0]
+	return &Session{
+		id:     id,
+		prefix: "session-" + id,
+	}
+}
+
+// ID returns the unique id of the session, which is the value of its
+// LabelSession label
+func (s *Session) ID() string {
+	return s.id
+}
+
+// NewRunner builds a runner whose container is labeled with the session and
+// named "<session prefix>-<name>-<short random id>". Calling WithName on the
+// runner opts out of the generated name but keeps the label.
+func (s *Session) NewRunner(name string) *ContainerRunner {
+	return NewContainerRunner().
+		WithNamePrefix(s.prefix+"-"+name).
+		WithLabel(LabelSession, s.id)
+}
+
+// NetworkName returns the name of the session's network called name, which
+// does not collide with networks of other sessions
+func (s *Session) NetworkName(name string) string {
+	return s.prefix + "-" + name
+}
+
+// CreateNetwork creates the session's network called name, see CreateNetwork.
+// Its actual name is NetworkName(name) and it is removed by Cleanup.
+func (s *Session) CreateNetwork(ctx context.Context, name string, opts NetworkOptions) (string, error) {
+	c, err := client.NewEnvClient()
+	if err != nil {
+		return "", fmt.Errorf("creating env client: %w", err)
+	}
+	defer c.Close()
+	return s.createNetwork(ctx, c, name, opts)
+}
+
+// createNetwork implements CreateNetwork with the given client
+func (s *Session) createNetwork(ctx context.Context, c client.NetworkAPIClient, name string, opts NetworkOptions) (string, error) {
+	labels := copyStringMap(opts.Labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[LabelSession] = s.id
+	opts.Labels = labels
+	return createNetwork(ctx, c, s.NetworkName(name), opts)
+}
+
+// Cleanup force removes every container of the session, running or not, and
+// then its networks. Unlike PruneOrphans, containers created by the current
+// process are removed too.
+func (s *Session) Cleanup(ctx context.Context) error {
+	c, err := client.NewEnvClient()
+	if err != nil {
+		return fmt.Errorf("creating env client: %w", err)
+	}
+	defer c.Close()
+	return s.cleanup(ctx, c)
+}
+
+// cleanup implements Cleanup with the given client
+func (s *Session) cleanup(ctx context.Context, c client.CommonAPIClient) error {
+	label := LabelSession + "=" + s.id
+	if _, err := removeLabeled(ctx, c, []string{label}, nil); err != nil {
+		return fmt.Errorf("cleaning up session %v: %w", s.id, err)
+	}
+
+	args := filters.NewArgs()
+	args.Add("label", label)
+	networks, err := c.NetworkList(ctx, types.NetworkListOptions{Filters: args})
+	if err != nil {
+		return fmt.Errorf("listing networks of session %v: %w", s.id, err)
+	}
+	for _, network := range networks {
+		if err := c.NetworkRemove(ctx, network.ID); err != nil && !client.IsErrNotFound(err) {
+			return fmt.Errorf("removing network %v: %w", network.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package runner
+
+import (
+	"context"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestSession(t *testing.T) {
+	session := NewSession()
+	var createdName string
+	runner := session.NewRunner("mongo").WithImage("mongo")
+	runner.client = &mockClient{
+		containerCreate: func(name string) (container.ContainerCreateCreatedBody, error) {
+			createdName = name
+			return container.ContainerCreateCreatedBody{ID: "id"}, nil
+		},
+	}
+	require.NoError(t, runner.Start(context.Background()))
+	require.True(t, strings.HasPrefix(createdName, "session-"+session.ID()+"-mongo-"))
+	require.Equal(t, session.ID(), runner.containerLabels()[LabelSession])
+}
+
+func TestSessionCleanup(t *testing.T) {
+	session := NewSession()
+	var filtered []string
+	var removedContainers, removedNetworks []string
+	c := &mockClient{
+		containerList: func(options types.ContainerListOptions) ([]types.Container, error) {
+			filtered = options.Filters.Get("label")
+			return []types.Container{
+				// Created by the current process, which PruneOrphans would keep
+				{ID: "own", Labels: map[string]string{LabelRunID: RunID}},
+				{ID: "other"},
+			}, nil
+		},
+		containerRemove: func(id string, options types.ContainerRemoveOptions) error {
+			removedContainers = append(removedContainers, id)
+			return nil
+		},
+		networkList: func(options types.NetworkListOptions) ([]types.NetworkResource, error) {
+			return []types.NetworkResource{{ID: "net", Name: session.NetworkName("backend")}}, nil
+		},
+		networkRemove: func(id string) error {
+			removedNetworks = append(removedNetworks, id)
+			return nil
+		},
+	}
+
+	require.NoError(t, session.cleanup(context.Background(), c))
+	require.Equal(t, []string{LabelSession + "=" + session.ID()}, filtered)
+	require.Equal(t, []string{"own", "other"}, removedContainers)
+	require.Equal(t, []string{"net"}, removedNetworks)
+}
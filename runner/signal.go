@@ -0,0 +1,76 @@
+package runner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// signals maps the numbers of the Linux signals to their names
+var signals = map[int]string{
+	1:  "SIGHUP",
+	2:  "SIGINT",
+	3:  "SIGQUIT",
+	4:  "SIGILL",
+	5:  "SIGTRAP",
+	6:  "SIGABRT",
+	7:  "SIGBUS",
+	8:  "SIGFPE",
+	9:  "SIGKILL",
+	10: "SIGUSR1",
+	11: "SIGSEGV",
+	12: "SIGUSR2",
+	13: "SIGPIPE",
+	14: "SIGALRM",
+	15: "SIGTERM",
+	16: "SIGSTKFLT",
+	17: "SIGCHLD",
+	18: "SIGCONT",
+	19: "SIGSTOP",
+	20: "SIGTSTP",
+	21: "SIGTTIN",
+	22: "SIGTTOU",
+	23: "SIGURG",
+	24: "SIGXCPU",
+	25: "SIGXFSZ",
+	26: "SIGVTALRM",
+	27: "SIGPROF",
+	28: "SIGWINCH",
+	29: "SIGIO",
+	30: "SIGPWR",
+	31: "SIGSYS",
+}
+
+// WithStopSignal sets the signal that Stop sends to the container before it
+// is killed, overriding the image's STOPSIGNAL. The signal is given by name,
+// with or without the SIG prefix ("SIGTERM", "TERM"), or by number ("15"),
+// as found in docker-compose files.
+func (r *ContainerRunner) WithStopSignal(signal string) *ContainerRunner {
+	name, err := normalizeSignal(signal)
+	if err != nil {
+		r.setErr(err)
+		return r
+	}
+	r.stopSignal = name
+	return r
+}
+
+// normalizeSignal returns the name of a signal given by name or number
+func normalizeSignal(signal string) (string, error) {
+	if n, err := strconv.Atoi(signal); err == nil {
+		if name, ok := signals[n]; ok {
+			return name, nil
+		}
+		return "", fmt.Errorf("unknown signal number %v", n)
+	}
+	name := strings.ToUpper(signal)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	for _, known := range signals {
+		if known == name {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("unknown signal %q", signal)
+}
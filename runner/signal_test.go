@@ -0,0 +1,34 @@
+package runner
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestWithStopSignal(t *testing.T) {
+	var testCases = []struct {
+		name   string
+		in     string
+		out    string
+		hasErr bool
+	}{
+		{name: "name", in: "SIGTERM", out: "SIGTERM"},
+		{name: "name without prefix", in: "quit", out: "SIGQUIT"},
+		{name: "number", in: "15", out: "SIGTERM"},
+		{name: "kill number", in: "9", out: "SIGKILL"},
+		{name: "unknown name", in: "SIGBOGUS", hasErr: true},
+		{name: "unknown number", in: "99", hasErr: true},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			runner := NewContainerRunner().WithStopSignal(c.in)
+			if c.hasErr {
+				require.Error(t, runner.err)
+				return
+			}
+			require.NoError(t, runner.err)
+			require.Equal(t, c.out, runner.containerConfig().StopSignal)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package runner
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"io"
+	"io/ioutil"
+	"path"
+)
+
+// CommitOption configures a single call to Commit
+type CommitOption func(*types.ContainerCommitOptions)
+
+// CommitChanges applies Dockerfile instructions, e.g. `CMD ["sh"]` or
+// "ENV DEBUG=1", to the committed image
+func CommitChanges(changes ...string) CommitOption {
+	return func(o *types.ContainerCommitOptions) {
+		o.Changes = append(o.Changes, changes...)
+	}
+}
+
+// CommitPause sets whether a running container is paused while it is
+// committed, which keeps its file system consistent. Like `docker commit`, it
+// is paused by default.
+func CommitPause(pause bool) CommitOption {
+	return func(o *types.ContainerCommitOptions) {
+		o.Pause = pause
+	}
+}
+
+// Commit captures the container's current state, stopped or running, as an
+// image tagged with reference (e.g. "debug/mongo:failed") and returns the new
+// image's id. This is useful to inspect a container that reached a bad state
+// after it was torn down.
+func (e *ContainerRunner) Commit(ctx context.Context, reference string, opts ...CommitOption) (string, error) {
+	// If we don't have a container id
+	if len(e.id) == 0 {
+		return "", ErrNoContainerId
+	}
+
+	options := types.ContainerCommitOptions{
+		Reference: reference,
+		Pause:     true,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	resp, err := e.client.ContainerCommit(ctx, e.id, options)
+	if err != nil {
+		return "", fmt.Errorf("committing container: %w", err)
+	}
+	return resp.ID, nil
+}
+
+// Export writes the container's entire file system to w as a tar archive.
+// Rather than copying a single path out of the container, this captures
+// everything, which is useful for forensic debugging of a stopped container.
+func (e *ContainerRunner) Export(ctx context.Context, w io.Writer) error {
+	// If we don't have a container id
+	if len(e.id) == 0 {
+		return ErrNoContainerId
+	}
+
+	tar, err := e.client.ContainerExport(ctx, e.id)
+	if err != nil {
+		return fmt.Errorf("exporting container: %w", err)
+	}
+	defer tar.Close()
+
+	if _, err := io.Copy(w, tar); err != nil {
+		return fmt.Errorf("copying container export: %w", err)
+	}
+	return nil
+}
+
+// maxSymlinks is how many symbolic links ReadFile follows before giving up,
+// as Linux does
+const maxSymlinks = 40
+
+// ReadFile returns the contents of the file at containerPath, following
+// symbolic links. It fails with ErrPathNotFound if the path does not exist
+// and with ErrIsDirectory if it is a directory. This is useful to assert on
+// files the container generated.
+func (e *ContainerRunner) ReadFile(ctx context.Context, containerPath string) ([]byte, error) {
+	// If we don't have a container id
+	if len(e.id) == 0 {
+		return nil, ErrNoContainerId
+	}
+	return e.readFile(ctx, containerPath, 0)
+}
+
+// readFile implements ReadFile, where links is the number of symbolic links
+// that were followed to get to containerPath
+func (e *ContainerRunner) readFile(ctx context.Context, containerPath string, links int) ([]byte, error) {
+	archive, stat, err := e.client.CopyFromContainer(ctx, e.id, containerPath)
+	if err != nil {
+		return nil, fmt.Errorf("copying %v from container: %w", containerPath, classifyError(err))
+	}
+	defer archive.Close()
+	if stat.Mode.IsDir() {
+		return nil, fmt.Errorf("reading %v: %w", containerPath, ErrIsDirectory)
+	}
+
+	r := tar.NewReader(archive)
+	header, err := r.Next()
+	if err != nil {
+		return nil, fmt.Errorf("reading archive of %v: %w", containerPath, err)
+	}
+	switch header.Typeflag {
+	case tar.TypeDir:
+		return nil, fmt.Errorf("reading %v: %w", containerPath, ErrIsDirectory)
+	case tar.TypeSymlink:
+		if links >= maxSymlinks {
+			return nil, fmt.Errorf("reading %v: too many levels of symbolic links", containerPath)
+		}
+		// The daemon resolves the target, older ones only archive the link
+		target := stat.LinkTarget
+		if len(target) == 0 {
+			target = header.Linkname
+			if !path.IsAbs(target) {
+				target = path.Join(path.Dir(containerPath), target)
+			}
+		}
+		return e.readFile(ctx, target, links+1)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading archive of %v: %w", containerPath, err)
+	}
+	return b, nil
+}
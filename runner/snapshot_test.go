@@ -0,0 +1,137 @@
+package runner
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestReadFile(t *testing.T) {
+	// archive returns the archive CopyFromContainer returns for header
+	archive := func(header *tar.Header, content []byte) io.ReadCloser {
+		var b bytes.Buffer
+		w := tar.NewWriter(&b)
+		header.Size = int64(len(content))
+		require.NoError(t, w.WriteHeader(header))
+		_, err := w.Write(content)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		return ioutil.NopCloser(&b)
+	}
+	content := []byte("listen_addresses = '*'\n")
+
+	runner := NewContainerRunner()
+	runner.id = "id"
+	runner.client = &mockClient{
+		copyFrom: func(path string) (io.ReadCloser, types.ContainerPathStat, error) {
+			switch path {
+			case "/etc/postgresql.conf":
+				return archive(&tar.Header{Name: "postgresql.conf", Mode: 0644}, content),
+					types.ContainerPathStat{Name: "postgresql.conf", Mode: 0644}, nil
+			case "/etc":
+				return ioutil.NopCloser(&bytes.Buffer{}), types.ContainerPathStat{Name: "etc", Mode: os.ModeDir | 0755}, nil
+			case "/etc/current.conf":
+				// Resolved by the daemon
+				return archive(&tar.Header{Name: "current.conf", Typeflag: tar.TypeSymlink, Linkname: "../etc/postgresql.conf"}, nil),
+					types.ContainerPathStat{Name: "current.conf", Mode: os.ModeSymlink | 0777, LinkTarget: "/etc/postgresql.conf"}, nil
+			case "/etc/relative.conf":
+				return archive(&tar.Header{Name: "relative.conf", Typeflag: tar.TypeSymlink, Linkname: "current.conf"}, nil),
+					types.ContainerPathStat{Name: "relative.conf", Mode: os.ModeSymlink | 0777}, nil
+			case "/etc/loop":
+				return archive(&tar.Header{Name: "loop", Typeflag: tar.TypeSymlink, Linkname: "loop"}, nil),
+					types.ContainerPathStat{Name: "loop", Mode: os.ModeSymlink | 0777}, nil
+			}
+			return nil, types.ContainerPathStat{}, errors.New("Error response from daemon: Could not find the file " + path + " in container id")
+		},
+	}
+
+	b, err := runner.ReadFile(context.Background(), "/etc/postgresql.conf")
+	require.NoError(t, err)
+	require.Equal(t, content, b)
+
+	b, err = runner.ReadFile(context.Background(), "/etc/current.conf")
+	require.NoError(t, err)
+	require.Equal(t, content, b)
+
+	b, err = runner.ReadFile(context.Background(), "/etc/relative.conf")
+	require.NoError(t, err)
+	require.Equal(t, content, b)
+
+	_, err = runner.ReadFile(context.Background(), "/etc/loop")
+	require.Error(t, err)
+
+	_, err = runner.ReadFile(context.Background(), "/etc")
+	require.True(t, errors.Is(err, ErrIsDirectory))
+
+	_, err = runner.ReadFile(context.Background(), "/missing")
+	require.True(t, errors.Is(err, ErrPathNotFound))
+}
+
+func TestCommit(t *testing.T) {
+	var options []types.ContainerCommitOptions
+	runner := NewContainerRunner()
+	_, err := runner.Commit(context.Background(), "debug/mongo:failed")
+	require.Equal(t, ErrNoContainerId, err)
+
+	runner.id = "id"
+	runner.client = &mockClient{
+		containerCommit: func(id string, o types.ContainerCommitOptions) (types.IDResponse, error) {
+			require.Equal(t, "id", id)
+			options = append(options, o)
+			return types.IDResponse{ID: "sha256:abc"}, nil
+		},
+	}
+	image, err := runner.Commit(context.Background(), "debug/mongo:failed")
+	require.NoError(t, err)
+	require.Equal(t, "sha256:abc", image)
+
+	_, err = runner.Commit(context.Background(), "debug/mongo:shell",
+		CommitChanges(`CMD ["sh"]`), CommitChanges("ENV DEBUG=1"), CommitPause(false))
+	require.NoError(t, err)
+	require.Equal(t, []types.ContainerCommitOptions{
+		{Reference: "debug/mongo:failed", Pause: true},
+		{Reference: "debug/mongo:shell", Changes: []string{`CMD ["sh"]`, "ENV DEBUG=1"}},
+	}, options)
+}
+
+// closeRecorder is a stream that records whether it was closed
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestExport(t *testing.T) {
+	runner := NewContainerRunner()
+	require.Equal(t, ErrNoContainerId, runner.Export(context.Background(), ioutil.Discard))
+
+	stream := &closeRecorder{Reader: strings.NewReader("filesystem archive")}
+	runner.id = "id"
+	runner.client = &mockClient{
+		containerExport: func(id string) (io.ReadCloser, error) {
+			require.Equal(t, "id", id)
+			return stream, nil
+		},
+	}
+	var b bytes.Buffer
+	require.NoError(t, runner.Export(context.Background(), &b))
+	require.Equal(t, "filesystem archive", b.String())
+	require.True(t, stream.closed)
+
+	stream = &closeRecorder{Reader: iotest.TimeoutReader(strings.NewReader("filesystem archive"))}
+	require.Error(t, runner.Export(context.Background(), &b))
+	require.True(t, stream.closed)
+}
@@ -0,0 +1,261 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types/mount"
+	"sort"
+	"time"
+)
+
+// RunnerSpec is a declarative description of a runner, mirroring the With*
+// builder methods, that can be unmarshalled from JSON or YAML. Fields that
+// are left empty keep the builder's defaults.
+type RunnerSpec struct {
+	Name          string            `json:"name,omitempty" yaml:"name,omitempty"`
+	Image         string            `json:"image,omitempty" yaml:"image,omitempty"`
+	RawImage      string            `json:"rawImage,omitempty" yaml:"rawImage,omitempty"`
+	ImageTarball  string            `json:"imageTarball,omitempty" yaml:"imageTarball,omitempty"`
+	PullPolicy    PullPolicy        `json:"pullPolicy,omitempty" yaml:"pullPolicy,omitempty"`
+	Ports         []int             `json:"ports,omitempty" yaml:"ports,omitempty"`
+	Env           map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	EnvFiles      []string          `json:"envFiles,omitempty" yaml:"envFiles,omitempty"`
+	Volumes       []VolumeSpec      `json:"volumes,omitempty" yaml:"volumes,omitempty"`
+	Sysctls       map[string]string `json:"sysctls,omitempty" yaml:"sysctls,omitempty"`
+	RestartPolicy *RestartSpec      `json:"restartPolicy,omitempty" yaml:"restartPolicy,omitempty"`
+	Timezone      string            `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+	NoNetwork     bool              `json:"noNetwork,omitempty" yaml:"noNetwork,omitempty"`
+	Networks      []NetworkSpec     `json:"networks,omitempty" yaml:"networks,omitempty"`
+	MacAddress    string            `json:"macAddress,omitempty" yaml:"macAddress,omitempty"`
+	PidMode       string            `json:"pidMode,omitempty" yaml:"pidMode,omitempty"`
+	IpcMode       string            `json:"ipcMode,omitempty" yaml:"ipcMode,omitempty"`
+	CgroupParent  string            `json:"cgroupParent,omitempty" yaml:"cgroupParent,omitempty"`
+	Runtime       string            `json:"runtime,omitempty" yaml:"runtime,omitempty"`
+	Isolation     string            `json:"isolation,omitempty" yaml:"isolation,omitempty"`
+	OomScoreAdj   *int              `json:"oomScoreAdj,omitempty" yaml:"oomScoreAdj,omitempty"`
+	Swappiness    *int64            `json:"memorySwappiness,omitempty" yaml:"memorySwappiness,omitempty"`
+	DockerSocket  bool              `json:"dockerSocket,omitempty" yaml:"dockerSocket,omitempty"`
+	ForceRecreate bool              `json:"forceRecreate,omitempty" yaml:"forceRecreate,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Wait          []WaitSpec        `json:"wait,omitempty" yaml:"wait,omitempty"`
+
+	// RemoveOnFinalization defaults to true when unset
+	RemoveOnFinalization        *bool `json:"removeOnFinalization,omitempty" yaml:"removeOnFinalization,omitempty"`
+	RemoveVolumesOnFinalization bool  `json:"removeVolumesOnFinalization,omitempty" yaml:"removeVolumesOnFinalization,omitempty"`
+	ForceRemoveOnFailure        bool  `json:"forceRemoveOnFailure,omitempty" yaml:"forceRemoveOnFailure,omitempty"`
+	KeepOnFailure               bool  `json:"keepOnFailure,omitempty" yaml:"keepOnFailure,omitempty"`
+	DisableAutoLabels           bool  `json:"disableAutoLabels,omitempty" yaml:"disableAutoLabels,omitempty"`
+	DetectLeaks                 bool  `json:"detectLeaks,omitempty" yaml:"detectLeaks,omitempty"`
+}
+
+// VolumeSpec describes a bind mount, see WithVolume
+type VolumeSpec struct {
+	HostPath      string            `json:"hostPath" yaml:"hostPath"`
+	ContainerPath string            `json:"containerPath" yaml:"containerPath"`
+	ReadOnly      bool              `json:"readOnly,omitempty" yaml:"readOnly,omitempty"`
+	Propagation   mount.Propagation `json:"propagation,omitempty" yaml:"propagation,omitempty"`
+	Consistency   Consistency       `json:"consistency,omitempty" yaml:"consistency,omitempty"`
+}
+
+// NetworkSpec describes a network attachment, see WithNetworkAlias
+type NetworkSpec struct {
+	Name    string   `json:"name" yaml:"name"`
+	Aliases []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+}
+
+// RestartSpec describes a restart policy, see WithRestartPolicy
+type RestartSpec struct {
+	Name       string `json:"name" yaml:"name"`
+	MaxRetries int    `json:"maxRetries,omitempty" yaml:"maxRetries,omitempty"`
+}
+
+// WaitSpec describes a wait strategy. Exactly one of Port, Log, HTTP and
+// Healthy must be set; HTTP also requires Port. Timeout is a duration string
+// such as "30s" and defaults to DefaultWaitTimeout.
+type WaitSpec struct {
+	Port    int    `json:"port,omitempty" yaml:"port,omitempty"`
+	Log     string `json:"log,omitempty" yaml:"log,omitempty"`
+	HTTP    string `json:"http,omitempty" yaml:"http,omitempty"`
+	Healthy bool   `json:"healthy,omitempty" yaml:"healthy,omitempty"`
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+// Validate returns an error if the spec has missing or contradictory
+// settings
+func (s RunnerSpec) Validate() error {
+	images := 0
+	for _, image := range []string{s.Image, s.RawImage, s.ImageTarball} {
+		if len(image) > 0 {
+			images++
+		}
+	}
+	if images != 1 {
+		return errors.New("exactly one of image, rawImage and imageTarball must be set")
+	}
+	if s.NoNetwork && len(s.Ports) > 0 {
+		return errors.New("ports cannot be published when the network is disabled")
+	}
+	if s.NoNetwork && len(s.Networks) > 0 {
+		return errors.New("networks cannot be attached when the network is disabled")
+	}
+	if s.NoNetwork && len(s.MacAddress) > 0 {
+		return errors.New("a mac address cannot be set when the network is disabled")
+	}
+	for _, w := range s.Wait {
+		if _, err := w.strategy(); err != nil {
+			return err
+		}
+		if s.NoNetwork && w.Port != 0 {
+			return errors.New("port and http waits require the network")
+		}
+	}
+	return nil
+}
+
+// strategy builds the wait strategy described by the spec
+func (w WaitSpec) strategy() (WaitStrategy, error) {
+	var timeout time.Duration
+	if len(w.Timeout) > 0 {
+		var err error
+		timeout, err = time.ParseDuration(w.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid wait timeout: %w", err)
+		}
+	}
+	switch {
+	case len(w.HTTP) > 0 && w.Port != 0 && len(w.Log) == 0 && !w.Healthy:
+		return ForHTTP(w.Port, w.HTTP, timeout), nil
+	case w.Port != 0 && len(w.Log) == 0 && !w.Healthy && len(w.HTTP) == 0:
+		return ForPort(w.Port, timeout), nil
+	case len(w.Log) > 0 && w.Port == 0 && !w.Healthy && len(w.HTTP) == 0:
+		return ForLog(w.Log, timeout), nil
+	case w.Healthy && w.Port == 0 && len(w.Log) == 0 && len(w.HTTP) == 0:
+		return ForHealthy(timeout), nil
+	}
+	return nil, errors.New("a wait must set exactly one of port, log, http (with port) and healthy")
+}
+
+// NewRunnerFromSpec builds a runner from a declarative spec. It returns an
+// error if the spec is invalid or any of its settings is rejected by the
+// corresponding builder method.
+func NewRunnerFromSpec(spec RunnerSpec) (*ContainerRunner, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid runner spec: %w", err)
+	}
+
+	opts := &ContainerRunnerOpts{
+		RemoveOnFinalization:        spec.RemoveOnFinalization == nil || *spec.RemoveOnFinalization,
+		RemoveVolumesOnFinalization: spec.RemoveVolumesOnFinalization,
+		ForceRemoveOnFailure:        spec.ForceRemoveOnFailure,
+		KeepOnFailure:               spec.KeepOnFailure,
+		DisableAutoLabels:           spec.DisableAutoLabels,
+		DetectLeaks:                 spec.DetectLeaks,
+	}
+	r := NewContainerRunner().
+		WithOptions(opts).
+		WithPorts(spec.Ports...).
+		WithEnvFile(spec.EnvFiles...)
+
+	switch {
+	case len(spec.Image) > 0:
+		r.WithImage(spec.Image)
+	case len(spec.RawImage) > 0:
+		r.WithRawImage(spec.RawImage)
+	default:
+		r.WithImageTarball(spec.ImageTarball)
+	}
+	if len(spec.Name) > 0 {
+		r.WithName(spec.Name)
+	}
+	if len(spec.PullPolicy) > 0 {
+		r.WithPullPolicy(spec.PullPolicy)
+	}
+	for _, key := range sortedKeys(spec.Env) {
+		r.WithEnvironmentVariable(key, spec.Env[key])
+	}
+	for _, v := range spec.Volumes {
+		var mountOpts []MountOption
+		if v.ReadOnly {
+			mountOpts = append(mountOpts, MountReadOnly())
+		}
+		if len(v.Propagation) > 0 {
+			mountOpts = append(mountOpts, MountPropagation(v.Propagation))
+		}
+		if len(v.Consistency) > 0 {
+			mountOpts = append(mountOpts, MountConsistency(v.Consistency))
+		}
+		r.WithVolume(v.HostPath, v.ContainerPath, mountOpts...)
+	}
+	for _, key := range sortedKeys(spec.Sysctls) {
+		r.WithSysctl(key, spec.Sysctls[key])
+	}
+	if spec.RestartPolicy != nil {
+		r.WithRestartPolicy(spec.RestartPolicy.Name, spec.RestartPolicy.MaxRetries)
+	}
+	if len(spec.Timezone) > 0 {
+		r.WithTimezone(spec.Timezone, false)
+	}
+	if spec.NoNetwork {
+		r.WithNoNetwork(true)
+	}
+	for _, n := range spec.Networks {
+		r.WithNetworkAlias(n.Name, n.Aliases...)
+	}
+	if len(spec.MacAddress) > 0 {
+		r.WithMacAddress(spec.MacAddress)
+	}
+	if len(spec.PidMode) > 0 {
+		r.WithPidMode(spec.PidMode)
+	}
+	if len(spec.IpcMode) > 0 {
+		r.WithIpcMode(spec.IpcMode)
+	}
+	if len(spec.CgroupParent) > 0 {
+		r.WithCgroupParent(spec.CgroupParent)
+	}
+	if len(spec.Runtime) > 0 {
+		r.WithRuntime(spec.Runtime)
+	}
+	if len(spec.Isolation) > 0 {
+		r.WithIsolation(spec.Isolation)
+	}
+	if spec.OomScoreAdj != nil {
+		r.WithOomScoreAdj(*spec.OomScoreAdj)
+	}
+	if spec.Swappiness != nil {
+		r.WithMemorySwappiness(*spec.Swappiness)
+	}
+	if spec.DockerSocket {
+		r.WithDockerSocket()
+	}
+	if spec.ForceRecreate {
+		r.WithForceRecreate()
+	}
+	for key, val := range spec.Labels {
+		r.WithLabel(key, val)
+	}
+	for key, val := range spec.Metadata {
+		r.WithMetadata(key, val)
+	}
+	for _, w := range spec.Wait {
+		// Validated above
+		strategy, _ := w.strategy()
+		r.WithWaitStrategy(strategy)
+	}
+
+	if r.err != nil {
+		return nil, fmt.Errorf("invalid runner spec: %w", r.err)
+	}
+	return r, nil
+}
+
+// sortedKeys returns the keys of m in ascending order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNewRunnerFromSpec(t *testing.T) {
+	var spec RunnerSpec
+	err := json.Unmarshal([]byte(`{
+		"name": "postgres",
+		"image": "postgres",
+		"ports": [5432],
+		"env": {"POSTGRES_PASSWORD": "secret", "POSTGRES_DB": "test"},
+		"wait": [{"port": 5432, "timeout": "30s"}]
+	}`), &spec)
+	require.NoError(t, err)
+
+	runner, err := NewRunnerFromSpec(spec)
+	require.NoError(t, err)
+	require.Equal(t, "docker.io/library/postgres", runner.Image())
+	require.Equal(t, []string{"POSTGRES_DB=test", "POSTGRES_PASSWORD=secret"}, runner.env)
+	require.Len(t, runner.waitStrategies, 1)
+	require.True(t, runner.opts.RemoveOnFinalization)
+}
+
+func TestNewRunnerFromSpecInvalid(t *testing.T) {
+	var testCases = []struct {
+		name string
+		spec RunnerSpec
+	}{
+		{
+			name: "no image",
+			spec: RunnerSpec{Name: "nothing"},
+		}, {
+			name: "ports without network",
+			spec: RunnerSpec{Image: "nginx", Ports: []int{80}, NoNetwork: true},
+		}, {
+			name: "ambiguous wait",
+			spec: RunnerSpec{Image: "nginx", Wait: []WaitSpec{{Port: 80, Log: "ready"}}},
+		}, {
+			name: "invalid option",
+			spec: RunnerSpec{Image: "nginx", PidMode: "bogus"},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := NewRunnerFromSpec(c.spec)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"time"
+)
+
+// Stats is a single resource usage sample of the container
+type Stats struct {
+	// Read is when the sample was taken
+	Read time.Time
+	// CPUPercent is the CPU usage since the previous sample, where 100%
+	// is one fully used CPU
+	CPUPercent float64
+	// MemoryUsage is the memory used by the container in bytes, excluding
+	// the page cache on cgroup v1 and inactive file pages on cgroup v2
+	MemoryUsage uint64
+	// MemoryLimit is the memory limit of the container in bytes
+	MemoryLimit uint64
+	// Labels are the labels of the container as reported by Docker,
+	// including LabelRunID and the ones inherited from its image, to group
+	// samples of several containers by
+	Labels map[string]string
+}
+
+// StatsSummary aggregates the samples taken by SampleStats
+type StatsSummary struct {
+	Samples         int
+	PeakCPUPercent  float64
+	AvgCPUPercent   float64
+	PeakMemoryUsage uint64
+	AvgMemoryUsage  uint64
+	// Labels are the labels of the container, see Stats
+	Labels map[string]string
+}
+
+// Stats returns a one-shot resource usage sample of the container
+func (e *ContainerRunner) Stats(ctx context.Context) (Stats, error) {
+	// If we don't have a container id
+	if len(e.id) == 0 {
+		return Stats{}, ErrNoContainerId
+	}
+
+	labels, err := e.inspectLabels(ctx)
+	if err != nil {
+		return Stats{}, err
+	}
+	resp, err := e.client.ContainerStats(ctx, e.id, false)
+	if err != nil {
+		return Stats{}, fmt.Errorf("getting container stats: %w", err)
+	}
+	defer resp.Body.Close()
+
+	s, err := decodeStats(json.NewDecoder(resp.Body))
+	if err != nil {
+		return Stats{}, fmt.Errorf("decoding container stats: %w", err)
+	}
+	var ncpu int
+	stats := newStats(s.StatsJSON, e.cpuCount(ctx, s, &ncpu))
+	stats.Labels = labels
+	return stats, nil
+}
+
+// SampleStats streams the container's resource usage for duration, keeping one
+// sample per interval, and returns the peak and average CPU and memory usage.
+// Docker produces a sample about once per second, so shorter intervals keep
+// every sample. The first sample Docker streams has no previous CPU usage to
+// compare to and is skipped. If ctx is done before duration elapsed, the summary of the
+// samples taken so far is returned along with the context's error.
+func (e *ContainerRunner) SampleStats(ctx context.Context, duration, interval time.Duration) (StatsSummary, error) {
+	// If we don't have a container id
+	if len(e.id) == 0 {
+		return StatsSummary{}, ErrNoContainerId
+	}
+
+	labels, err := e.inspectLabels(ctx)
+	if err != nil {
+		return StatsSummary{}, err
+	}
+	streamCtx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+	resp, err := e.client.ContainerStats(streamCtx, e.id, true)
+	if err != nil {
+		return StatsSummary{}, fmt.Errorf("streaming container stats: %w", err)
+	}
+	defer resp.Body.Close()
+
+	summary := StatsSummary{Labels: labels}
+	var cpuTotal float64
+	var memoryTotal uint64
+	var last time.Time
+	var ncpu int
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		s, err := decodeStats(decoder)
+		if err != nil {
+			if ctx.Err() != nil {
+				return summary, ctx.Err()
+			}
+			if streamCtx.Err() != nil {
+				// duration elapsed
+				return summary, nil
+			}
+			return summary, fmt.Errorf("decoding container stats: %w", err)
+		}
+		if s.PreCPUStats.SystemUsage == 0 {
+			// The CPU usage would be an average since the container started
+			continue
+		}
+		if !last.IsZero() && s.Read.Sub(last) < interval {
+			continue
+		}
+		last = s.Read
+
+		sample := newStats(s.StatsJSON, e.cpuCount(ctx, s, &ncpu))
+		summary.Samples++
+		cpuTotal += sample.CPUPercent
+		memoryTotal += sample.MemoryUsage
+		if sample.CPUPercent > summary.PeakCPUPercent {
+			summary.PeakCPUPercent = sample.CPUPercent
+		}
+		if sample.MemoryUsage > summary.PeakMemoryUsage {
+			summary.PeakMemoryUsage = sample.MemoryUsage
+		}
+		summary.AvgCPUPercent = cpuTotal / float64(summary.Samples)
+		summary.AvgMemoryUsage = memoryTotal / uint64(summary.Samples)
+	}
+}
+
+// statsSample is a raw stats sample along with the fields that the vendored
+// types lack
+type statsSample struct {
+	types.StatsJSON
+	// onlineCPUs is the number of CPUs available to the container, which
+	// newer daemons report on both cgroup v1 and v2
+	onlineCPUs int
+}
+
+// decodeStats decodes the next stats sample of d
+func decodeStats(d *json.Decoder) (statsSample, error) {
+	var raw json.RawMessage
+	if err := d.Decode(&raw); err != nil {
+		return statsSample{}, err
+	}
+	var s statsSample
+	if err := json.Unmarshal(raw, &s.StatsJSON); err != nil {
+		return statsSample{}, err
+	}
+	var extra struct {
+		CPUStats struct {
+			OnlineCPUs int `json:"online_cpus"`
+		} `json:"cpu_stats"`
+	}
+	if err := json.Unmarshal(raw, &extra); err != nil {
+		return statsSample{}, err
+	}
+	s.onlineCPUs = extra.CPUStats.OnlineCPUs
+	return s, nil
+}
+
+// cpuCount returns the number of CPUs the CPU usage of s is relative to. If
+// the sample reports neither online CPUs nor per-CPU usage, which is absent on
+// cgroup v2, the daemon's CPU count is used and cached in ncpu.
+func (e *ContainerRunner) cpuCount(ctx context.Context, s statsSample, ncpu *int) int {
+	if s.onlineCPUs > 0 {
+		return s.onlineCPUs
+	}
+	if n := len(s.CPUStats.CPUUsage.PercpuUsage); n > 0 {
+		return n
+	}
+	if *ncpu == 0 {
+		*ncpu = 1
+		if info, err := e.client.Info(ctx); err != nil {
+			e.logger.Warnf("getting the CPU count of the daemon: %v", err)
+		} else if info.NCPU > 0 {
+			*ncpu = info.NCPU
+		}
+	}
+	return *ncpu
+}
+
+// newStats computes the usage of a raw stats sample the same way `docker
+// stats` does, for a container that can use cpus CPUs
+func newStats(s types.StatsJSON, cpus int) Stats {
+	stats := Stats{
+		Read:        s.Read,
+		MemoryUsage: s.MemoryStats.Usage,
+		MemoryLimit: s.MemoryStats.Limit,
+	}
+	// cgroup v1 reports the page cache, cgroup v2 only inactive file pages
+	cache, ok := s.MemoryStats.Stats["cache"]
+	if !ok {
+		cache = s.MemoryStats.Stats["inactive_file"]
+	}
+	if cache <= stats.MemoryUsage {
+		stats.MemoryUsage -= cache
+	}
+
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+	if cpuDelta > 0 && systemDelta > 0 {
+		stats.CPUPercent = cpuDelta / systemDelta * float64(cpus) * 100
+	}
+	return stats
+}
@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		ncpu    int
+		cpu     float64
+		memory  uint64
+	}{
+		{
+			name: "cgroup v1",
+			payload: `{
+				"cpu_stats": {"cpu_usage": {"total_usage": 400, "percpu_usage": [300, 100]}, "system_cpu_usage": 2000},
+				"precpu_stats": {"cpu_usage": {"total_usage": 200, "percpu_usage": [150, 50]}, "system_cpu_usage": 1000},
+				"memory_stats": {"usage": 1000, "limit": 4000, "stats": {"cache": 200, "inactive_file": 50}}
+			}`,
+			cpu:    40,
+			memory: 800,
+		},
+		{
+			name: "cgroup v2",
+			payload: `{
+				"cpu_stats": {"cpu_usage": {"total_usage": 300}, "system_cpu_usage": 2000, "online_cpus": 4},
+				"precpu_stats": {"cpu_usage": {"total_usage": 200}, "system_cpu_usage": 1000, "online_cpus": 4},
+				"memory_stats": {"usage": 1000, "limit": 4000, "stats": {"inactive_file": 100}}
+			}`,
+			cpu:    40,
+			memory: 900,
+		},
+		{
+			name: "cgroup v2 without online cpus",
+			payload: `{
+				"cpu_stats": {"cpu_usage": {"total_usage": 300}, "system_cpu_usage": 2000},
+				"precpu_stats": {"cpu_usage": {"total_usage": 200}, "system_cpu_usage": 1000},
+				"memory_stats": {"usage": 1000, "limit": 4000}
+			}`,
+			ncpu:   8,
+			cpu:    80,
+			memory: 1000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := NewContainerRunner()
+			runner.id = "id"
+			runner.client = &mockClient{
+				containerStats: func(ctx context.Context, stream bool) (io.ReadCloser, error) {
+					require.False(t, stream)
+					return ioutil.NopCloser(strings.NewReader(tt.payload)), nil
+				},
+				info: func() (types.Info, error) {
+					return types.Info{NCPU: tt.ncpu}, nil
+				},
+				containerInspect: labeledContainer,
+			}
+			stats, err := runner.Stats(context.Background())
+			require.NoError(t, err)
+			require.InDelta(t, tt.cpu, stats.CPUPercent, 0.001)
+			require.Equal(t, tt.memory, stats.MemoryUsage)
+			require.Equal(t, uint64(4000), stats.MemoryLimit)
+			require.Equal(t, "postgres", stats.Labels["org.opencontainers.image.title"])
+		})
+	}
+}
+
+// labeledContainer is the inspect result of a container with a label set by
+// the runner and one inherited from its image
+func labeledContainer(id string) (types.ContainerJSON, error) {
+	info := runningContainer()
+	info.Config = &container.Config{Labels: map[string]string{
+		LabelRunID:                       RunID,
+		"org.opencontainers.image.title": "postgres",
+	}}
+	return info, nil
+}
+
+func TestSampleStats(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	// sample returns a cgroup v2 sample read after offset, using cpu percent
+	// of one CPU and memory bytes
+	sample := func(offset time.Duration, preSystem, cpu, memory int) string {
+		return fmt.Sprintf(`{
+			"read": %q,
+			"cpu_stats": {"cpu_usage": {"total_usage": %v}, "system_cpu_usage": 1100, "online_cpus": 1},
+			"precpu_stats": {"cpu_usage": {"total_usage": 0}, "system_cpu_usage": %v, "online_cpus": 1},
+			"memory_stats": {"usage": %v}
+		}`, start.Add(offset).Format(time.RFC3339Nano), cpu, preSystem, memory)
+	}
+	samples := []string{
+		// Streamed first, without a previous sample
+		sample(0, 0, 1000, 999),
+		sample(time.Second, 1000, 10, 100),
+		// Within the interval of the previous sample
+		sample(1500*time.Millisecond, 1000, 90, 900),
+		sample(2*time.Second, 1000, 30, 300),
+		sample(3*time.Second, 1000, 20, 200),
+	}
+
+	inspections := 0
+	runner := NewContainerRunner()
+	runner.id = "id"
+	runner.client = &mockClient{
+		containerInspect: func(id string) (types.ContainerJSON, error) {
+			inspections++
+			return labeledContainer(id)
+		},
+		containerStats: func(ctx context.Context, stream bool) (io.ReadCloser, error) {
+			require.True(t, stream)
+			r, w := io.Pipe()
+			go func() {
+				for _, s := range samples {
+					w.Write([]byte(s))
+				}
+				// The daemon keeps streaming until the request is cancelled
+				<-ctx.Done()
+				w.CloseWithError(ctx.Err())
+			}()
+			return r, nil
+		},
+	}
+	summary, err := runner.SampleStats(context.Background(), 100*time.Millisecond, time.Second)
+	require.NoError(t, err)
+	require.Equal(t, 3, summary.Samples)
+	require.InDelta(t, 30, summary.PeakCPUPercent, 0.001)
+	require.InDelta(t, 20, summary.AvgCPUPercent, 0.001)
+	require.Equal(t, uint64(300), summary.PeakMemoryUsage)
+	require.Equal(t, uint64(200), summary.AvgMemoryUsage)
+	require.Equal(t, 1, inspections)
+	require.Equal(t, RunID, summary.Labels[LabelRunID])
+	require.Equal(t, "postgres", summary.Labels["org.opencontainers.image.title"])
+}
@@ -0,0 +1,190 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultStopTimeout is how long Stop waits for the container to exit
+	// before it is killed
+	DefaultStopTimeout = time.Minute
+	// DefaultExitPollInterval is how often StopGraceful inspects the
+	// container while waiting for it to exit
+	DefaultExitPollInterval = 100 * time.Millisecond
+)
+
+// StopOption overrides the runner's options for a single call to Stop
+type StopOption func(*stopConfig)
+
+// stopConfig is the behavior of a single call to Stop
+type stopConfig struct {
+	remove   bool
+	force    bool
+	timeout  time.Duration
+	deadline time.Duration
+}
+
+// stopConfig returns the stop behavior configured by the runner's options
+func (e *ContainerRunner) stopConfig() stopConfig {
+	return stopConfig{
+		remove:  e.opts.RemoveOnFinalization,
+		force:   e.opts.ForceRemoveOnFailure,
+		timeout: DefaultStopTimeout,
+	}
+}
+
+// StopRemove overrides ContainerRunnerOpts.RemoveOnFinalization
+func StopRemove(remove bool) StopOption {
+	return func(c *stopConfig) {
+		c.remove = remove
+	}
+}
+
+// StopForce overrides ContainerRunnerOpts.ForceRemoveOnFailure
+func StopForce(force bool) StopOption {
+	return func(c *stopConfig) {
+		c.force = force
+	}
+}
+
+// StopTimeout sets how long Docker waits for the container to exit before it
+// is killed, overriding DefaultStopTimeout
+func StopTimeout(timeout time.Duration) StopOption {
+	return func(c *stopConfig) {
+		c.timeout = timeout
+	}
+}
+
+// StopDeadline bounds how long Stop waits for the daemon to stop the
+// container, independently of the grace period set with StopTimeout. A daemon
+// that ignores the stop request would otherwise block Stop indefinitely. Once
+// the deadline has elapsed, Stop fails with ErrStopTimeout, or falls back to
+// force removal if StopForce applies. It should be longer than the grace
+// period, as Docker still needs to kill the container after it; zero means no
+// bound other than ctx.
+func StopDeadline(deadline time.Duration) StopOption {
+	return func(c *stopConfig) {
+		c.deadline = deadline
+	}
+}
+
+// StopResult describes how a container exited after StopGraceful
+type StopResult struct {
+	// ExitCode is the exit code reported by the container
+	ExitCode int
+	// Signal is the last signal that was sent to the container
+	Signal string
+	// Killed is true if the container did not exit within the grace period
+	// and had to be killed with SIGKILL
+	Killed bool
+	// Duration is how long it took the container to exit
+	Duration time.Duration
+}
+
+// StopGraceful sends signal, given as for WithStopSignal, to the container and
+// waits up to grace for it to exit, escalating to SIGKILL once grace has
+// elapsed. A grace of zero or less sends SIGKILL right away. Unlike Stop, this
+// gives precise control over the shutdown sequence, which is useful for
+// testing an application's own shutdown behavior. The container is removed
+// afterwards according to the runner's options.
+func (e *ContainerRunner) StopGraceful(ctx context.Context, signal string, grace time.Duration) (StopResult, error) {
+	e.logger.Infof("stopping container with %v", signal)
+	// If we don't have a container id
+	if len(e.id) == 0 {
+		return StopResult{}, ErrNoContainerId
+	}
+
+	signal, err := normalizeSignal(signal)
+	if err != nil {
+		return StopResult{}, err
+	}
+	started := time.Now()
+	result := StopResult{Signal: signal}
+	exited, code := false, 0
+	if grace > 0 {
+		if err := e.client.ContainerKill(ctx, e.id, signal); err != nil {
+			return result, fmt.Errorf("sending %v to container: %w", signal, err)
+		}
+		if exited, code, err = e.pollExit(ctx, grace); err != nil {
+			return result, err
+		}
+		if !exited {
+			e.logger.Warnf("container did not exit within %v, sending SIGKILL", grace)
+		}
+	}
+	if !exited {
+		result.Signal = "SIGKILL"
+		result.Killed = true
+		if err := e.client.ContainerKill(ctx, e.id, "SIGKILL"); err != nil {
+			return result, fmt.Errorf("killing container: %w", err)
+		}
+		// A killed container exits promptly, but don't rely on the daemon
+		// for it
+		if exited, code, err = e.pollExit(ctx, DefaultWaitTimeout); err != nil {
+			return result, err
+		}
+		if !exited {
+			return result, fmt.Errorf("%w: container still running %v after SIGKILL", ErrStopTimeout, DefaultWaitTimeout)
+		}
+	}
+	result.ExitCode = code
+	result.Duration = time.Since(started)
+	e.logger.Infoln("container stopped")
+	e.untrackLeak()
+
+	if e.opts.RemoveOnFinalization {
+		return result, e.remove(ctx, e.opts.ForceRemoveOnFailure)
+	}
+	return result, nil
+}
+
+// pollExit inspects the container until it is no longer running and returns
+// its exit code. It gives up after timeout, or when ctx is done if timeout is
+// zero, returning false.
+func (e *ContainerRunner) pollExit(ctx context.Context, timeout time.Duration) (bool, int, error) {
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		deadline = time.After(timeout)
+	}
+	ticker := time.NewTicker(DefaultExitPollInterval)
+	defer ticker.Stop()
+	for {
+		info, err := e.client.ContainerInspect(ctx, e.id)
+		if err != nil {
+			return false, 0, fmt.Errorf("inspecting container: %w", err)
+		}
+		if info.State != nil && !info.State.Running {
+			return true, info.State.ExitCode, nil
+		}
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			return false, 0, nil
+		case <-ctx.Done():
+			return false, 0, ctx.Err()
+		}
+	}
+}
+
+// Detach releases the container from the runner without stopping it, and
+// returns its id. The container keeps running under its name, e.g. for manual
+// debugging after the test process exited, and must be removed by hand.
+// Afterwards Stop and the cleanup of StartForTest do nothing. The runner's
+// options are left unchanged, so the container of a later Start is stopped
+// and removed as usual.
+func (e *ContainerRunner) Detach() (string, error) {
+	// If we don't have a container id
+	if len(e.id) == 0 {
+		return "", ErrNoContainerId
+	}
+
+	id := e.id
+	e.logger.Infof("detaching from container %v (%v)", e.name, id)
+	// A detached container is left running on purpose, not leaked
+	e.untrackLeak()
+	e.id = ""
+	e.removed = true
+	return id, nil
+}
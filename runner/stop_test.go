@@ -0,0 +1,192 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestStopTwice(t *testing.T) {
+	stops := 0
+	runner := NewContainerRunner().WithImage("mongo")
+	runner.client = &mockClient{
+		containerStop: func(ctx context.Context, id string) error {
+			stops++
+			return nil
+		},
+	}
+	require.NoError(t, runner.Start(context.Background()))
+
+	require.NoError(t, runner.Stop(context.Background()))
+	require.NoError(t, runner.Stop(context.Background()))
+	require.Equal(t, 1, stops)
+	require.Empty(t, runner.id)
+}
+
+func TestStopDeadline(t *testing.T) {
+	runner := NewContainerRunner().WithImage("mongo")
+	runner.client = &mockClient{
+		// A daemon that never answers the stop request
+		containerStop: func(ctx context.Context, id string) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	require.NoError(t, runner.Start(context.Background()))
+
+	err := runner.Stop(context.Background(), StopDeadline(50*time.Millisecond))
+	require.True(t, errors.Is(err, ErrStopTimeout))
+
+	require.NoError(t, runner.Stop(context.Background(), StopDeadline(50*time.Millisecond), StopForce(true)))
+	require.Empty(t, runner.id)
+}
+
+func TestStopDefault(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var stopCtx context.Context
+	runner := NewContainerRunner().WithImage("mongo").WithContext(ctx)
+	runner.client = &mockClient{
+		containerStop: func(ctx context.Context, id string) error {
+			stopCtx = ctx
+			return nil
+		},
+	}
+	require.NoError(t, runner.Start(context.Background()))
+
+	cancel()
+	require.NoError(t, runner.StopDefault(StopRemove(false)))
+	require.Equal(t, context.Canceled, stopCtx.Err())
+}
+
+func TestDetach(t *testing.T) {
+	stops := 0
+	runner := NewContainerRunner().WithImage("mongo").WithOptions(&ContainerRunnerOpts{
+		RemoveOnFinalization: true,
+		DetectLeaks:          true,
+	})
+	runner.client = &mockClient{
+		containerStop: func(ctx context.Context, id string) error {
+			stops++
+			return nil
+		},
+	}
+	_, err := runner.Detach()
+	require.Equal(t, ErrNoContainerId, err)
+	require.NoError(t, runner.Start(context.Background()))
+
+	id, err := runner.Detach()
+	require.NoError(t, err)
+	require.Equal(t, "id", id)
+	require.NoError(t, LeakCheck())
+	require.NoError(t, runner.Stop(context.Background()))
+	require.Equal(t, 0, stops)
+
+	require.NoError(t, runner.Start(context.Background()))
+	require.NoError(t, runner.Stop(context.Background()))
+	require.Equal(t, 1, stops)
+}
+
+func TestStopGraceful(t *testing.T) {
+	tests := []struct {
+		name    string
+		grace   time.Duration
+		exitOn  string
+		signals []string
+		killed  bool
+	}{
+		{"exits within grace", time.Second, "SIGTERM", []string{"SIGTERM"}, false},
+		{"escalates to SIGKILL", 50 * time.Millisecond, "SIGKILL", []string{"SIGTERM", "SIGKILL"}, true},
+		{"zero grace", 0, "SIGKILL", []string{"SIGKILL"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var signals []string
+			exited := false
+			runner := NewContainerRunner().WithImage("mongo")
+			runner.client = &mockClient{
+				containerKill: func(id, signal string) error {
+					signals = append(signals, signal)
+					exited = exited || signal == tt.exitOn
+					return nil
+				},
+				containerInspect: func(id string) (types.ContainerJSON, error) {
+					info := runningContainer()
+					if exited {
+						info.State = &types.ContainerState{ExitCode: 137}
+					}
+					return info, nil
+				},
+			}
+			require.NoError(t, runner.Start(context.Background()))
+
+			result, err := runner.StopGraceful(context.Background(), "SIGTERM", tt.grace)
+			require.NoError(t, err)
+			require.Equal(t, tt.signals, signals)
+			require.Equal(t, tt.killed, result.Killed)
+			require.Equal(t, signals[len(signals)-1], result.Signal)
+			require.Equal(t, 137, result.ExitCode)
+		})
+	}
+}
+
+func TestStopRemoveBusy(t *testing.T) {
+	defer func(backoff time.Duration) { removeBackoff = backoff }(removeBackoff)
+	removeBackoff = time.Millisecond
+
+	busy := errors.New("Error response from daemon: driver \"overlay2\" failed to remove root filesystem: device or resource busy")
+	for _, tc := range []struct {
+		name     string
+		failures int
+		err      error
+		removals int
+	}{
+		{name: "busy then removed", failures: 3, err: busy, removals: 4},
+		{name: "always busy", failures: DefaultRemoveAttempts, err: busy, removals: DefaultRemoveAttempts},
+		{name: "not busy", failures: 1, err: errors.New("permission denied"), removals: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			removals := 0
+			runner := NewContainerRunner().WithImage("mongo")
+			runner.client = &mockClient{
+				containerRemove: func(id string, options types.ContainerRemoveOptions) error {
+					removals++
+					if removals <= tc.failures {
+						return tc.err
+					}
+					return nil
+				},
+			}
+			require.NoError(t, runner.Start(context.Background()))
+			err := runner.Stop(context.Background(), StopForce(false))
+			require.Equal(t, tc.removals, removals)
+			if tc.failures < tc.removals {
+				require.NoError(t, err)
+				require.Empty(t, runner.id)
+				return
+			}
+			require.True(t, errors.Is(err, tc.err))
+		})
+	}
+}
+
+func TestRemoveVolumesOnFinalization(t *testing.T) {
+	for _, removeVolumes := range []bool{false, true} {
+		var options []types.ContainerRemoveOptions
+		runner := NewContainerRunner().WithImage("postgres").WithOptions(&ContainerRunnerOpts{
+			RemoveOnFinalization:        true,
+			RemoveVolumesOnFinalization: removeVolumes,
+		})
+		runner.client = &mockClient{
+			containerRemove: func(id string, o types.ContainerRemoveOptions) error {
+				options = append(options, o)
+				return nil
+			},
+		}
+		require.NoError(t, runner.Start(context.Background()))
+		require.NoError(t, runner.Stop(context.Background()))
+		require.Equal(t, []types.ContainerRemoveOptions{{RemoveVolumes: removeVolumes}}, options)
+	}
+}
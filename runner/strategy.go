@@ -0,0 +1,496 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types/container"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultWaitTimeout is how long the built-in wait strategies wait for
+	// their condition when no timeout is given
+	DefaultWaitTimeout = time.Minute
+	// DefaultPollInterval is how often the built-in wait strategies check
+	// their condition
+	DefaultPollInterval = 100 * time.Millisecond
+)
+
+// WaitStrategy decides when a started container is ready to be used
+type WaitStrategy interface {
+	// WaitUntilReady blocks until the container managed by r is ready, or
+	// returns an error if it cannot become ready
+	WaitUntilReady(ctx context.Context, r *ContainerRunner) error
+}
+
+// WithWaitStrategy makes Start block until the container is ready according
+// to strategy. Strategies are waited on in the order they were added.
+func (r *ContainerRunner) WithWaitStrategy(strategy WaitStrategy) *ContainerRunner {
+	r.waitStrategies = append(r.waitStrategies, strategy)
+	return r
+}
+
+// WithWaitForPort makes Start block until the host port bound to
+// containerPort accepts TCP connections, see ForPort
+func (r *ContainerRunner) WithWaitForPort(containerPort int, timeout time.Duration) *ContainerRunner {
+	return r.WithWaitStrategy(ForPort(containerPort, timeout))
+}
+
+// WithWaitForNetworkPort makes Start block until containerPort accepts TCP
+// connections from inside the container's network, see ForNetworkPort
+func (r *ContainerRunner) WithWaitForNetworkPort(containerPort int, timeout time.Duration) *ContainerRunner {
+	return r.WithWaitStrategy(ForNetworkPort(containerPort, timeout))
+}
+
+// WithWaitForLog makes Start block until a log line contains substring, see
+// ForLog
+func (r *ContainerRunner) WithWaitForLog(substring string, timeout time.Duration) *ContainerRunner {
+	return r.WithWaitStrategy(ForLog(substring, timeout))
+}
+
+// WithWaitForLogOccurrences makes Start block until count log lines contain
+// substring, see ForLogOccurrences
+func (r *ContainerRunner) WithWaitForLogOccurrences(substring string, count int, timeout time.Duration) *ContainerRunner {
+	if count < 1 {
+		r.setErr(fmt.Errorf("log occurrences %v must be at least 1", count))
+		return r
+	}
+	return r.WithWaitStrategy(ForLogOccurrences(substring, count, timeout))
+}
+
+// WithWaitForHTTP makes Start block until path answers with a 2xx status, see
+// ForHTTP
+func (r *ContainerRunner) WithWaitForHTTP(containerPort int, path string, timeout time.Duration) *ContainerRunner {
+	return r.WithWaitStrategy(ForHTTP(containerPort, path, timeout))
+}
+
+// WithWaitForFile makes Start block until path exists in the container, see
+// ForFile
+func (r *ContainerRunner) WithWaitForFile(path string, interval, timeout time.Duration) *ContainerRunner {
+	if interval < 0 {
+		r.setErr(fmt.Errorf("poll interval %v must not be negative", interval))
+		return r
+	}
+	return r.WithWaitStrategy(ForFile(path, interval, timeout))
+}
+
+// WithWaitForHealthy makes Start block until Docker reports the container as
+// healthy, see ForHealthy
+func (r *ContainerRunner) WithWaitForHealthy(timeout time.Duration) *ContainerRunner {
+	return r.WithWaitStrategy(ForHealthy(timeout))
+}
+
+// WithWaitForAll makes Start block until all of the strategies passed within
+// timeout, see ForAll
+func (r *ContainerRunner) WithWaitForAll(timeout time.Duration, strategies ...WaitStrategy) *ContainerRunner {
+	if timeout < 0 {
+		r.setErr(fmt.Errorf("wait timeout %v must not be negative", timeout))
+		return r
+	}
+	return r.WithWaitStrategy(ForAll(timeout, strategies...))
+}
+
+// WithHealthCheck defines the container's healthcheck, overriding the one of
+// the image, so that ForHealthy also works with images that don't define
+// one. test uses the HEALTHCHECK forms: {"CMD", executable, args...} runs the
+// executable directly and {"CMD-SHELL", command} runs command with the
+// container's shell. Zero durations and retries use Docker's defaults.
+func (r *ContainerRunner) WithHealthCheck(test []string, interval, timeout time.Duration, retries int) *ContainerRunner {
+	switch {
+	case len(test) >= 2 && test[0] == "CMD":
+	case len(test) == 2 && test[0] == "CMD-SHELL":
+	default:
+		r.setErr(fmt.Errorf("invalid healthcheck %q: must be CMD with arguments or CMD-SHELL with a command", test))
+		return r
+	}
+	if interval < 0 || timeout < 0 || retries < 0 {
+		r.setErr(errors.New("healthcheck interval, timeout and retries must not be negative"))
+		return r
+	}
+	r.healthcheck = &container.HealthConfig{
+		Test:     test,
+		Interval: interval,
+		Timeout:  timeout,
+		Retries:  retries,
+	}
+	return r
+}
+
+// Wait blocks until the started container is ready according to all of the
+// strategies, which are waited on in order. Unlike the WithWait* options,
+// this allows starting many containers first and waiting on them afterwards.
+func (e *ContainerRunner) Wait(ctx context.Context, strategies ...WaitStrategy) error {
+	_, err := e.WaitReady(ctx, strategies...)
+	return err
+}
+
+// ReadinessResult describes how a container became ready
+type ReadinessResult struct {
+	// Strategies holds the result of every strategy that passed, in the
+	// order they were waited on
+	Strategies []StrategyResult
+	// Duration is how long waiting on all of the strategies took
+	Duration time.Duration
+}
+
+// StrategyResult describes how a single wait strategy passed
+type StrategyResult struct {
+	// Strategy describes the strategy, e.g. "port 5432"
+	Strategy string
+	// Duration is how long the strategy took to pass
+	Duration time.Duration
+	// Attempts is how many times the strategy checked its condition, such as
+	// the number of polls or of log lines read. It includes the checks of
+	// strategies waited on concurrently on the same runner.
+	Attempts int
+}
+
+// WaitReady is like Wait but also returns how the container became ready,
+// which allows tracking startup latency
+func (e *ContainerRunner) WaitReady(ctx context.Context, strategies ...WaitStrategy) (ReadinessResult, error) {
+	// If we don't have a container id
+	if len(e.id) == 0 {
+		return ReadinessResult{}, ErrNoContainerId
+	}
+	var result ReadinessResult
+	start := time.Now()
+	for _, s := range strategies {
+		e.logger.Infof("waiting for %v", s)
+		attempts := atomic.LoadInt32(&e.attempts)
+		began := time.Now()
+		if err := s.WaitUntilReady(ctx, e); err != nil {
+			return result, fmt.Errorf("waiting for %v: %w", s, err)
+		}
+		result.Strategies = append(result.Strategies, StrategyResult{
+			Strategy: fmt.Sprint(s),
+			Duration: time.Since(began),
+			Attempts: int(atomic.LoadInt32(&e.attempts) - attempts),
+		})
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// Readiness returns how the container became ready during the last Start,
+// according to the strategies added with WithWaitStrategy
+func (r *ContainerRunner) Readiness() ReadinessResult {
+	return r.readiness
+}
+
+// ForPort is ready once the host port bound to containerPort accepts TCP
+// connections. A zero timeout means DefaultWaitTimeout.
+func ForPort(containerPort int, timeout time.Duration) WaitStrategy {
+	return &portStrategy{port: containerPort, timeout: timeout}
+}
+
+type portStrategy struct {
+	port    int
+	timeout time.Duration
+}
+
+func (s *portStrategy) String() string {
+	return fmt.Sprintf("port %v", s.port)
+}
+
+func (s *portStrategy) WaitUntilReady(ctx context.Context, r *ContainerRunner) error {
+	return r.poll(ctx, s.timeout, func(ctx context.Context) (bool, error) {
+		return r.dial(ctx, s.port), nil
+	})
+}
+
+// ForLog is ready once a line of the container's stdout or stderr contains
+// substring. A zero timeout means DefaultWaitTimeout.
+func ForLog(substring string, timeout time.Duration) WaitStrategy {
+	return &logStrategy{substring: substring, count: 1, timeout: timeout}
+}
+
+// ForLogOccurrences is ready once count lines of the container's stdout or
+// stderr contain substring, e.g. when every worker of a service logs that it
+// is ready. A zero timeout means DefaultWaitTimeout.
+func ForLogOccurrences(substring string, count int, timeout time.Duration) WaitStrategy {
+	return &logStrategy{substring: substring, count: count, timeout: timeout}
+}
+
+type logStrategy struct {
+	substring string
+	count     int
+	timeout   time.Duration
+}
+
+func (s *logStrategy) String() string {
+	if s.count > 1 {
+		return fmt.Sprintf("log %q %v times", s.substring, s.count)
+	}
+	return fmt.Sprintf("log %q", s.substring)
+}
+
+func (s *logStrategy) WaitUntilReady(ctx context.Context, r *ContainerRunner) error {
+	seen := 0
+	err := r.scanLogs(ctx, orDefaultTimeout(s.timeout), func(line string) bool {
+		if strings.Contains(line, s.substring) {
+			seen++
+		}
+		return seen >= s.count
+	})
+	if err != nil && s.count > 1 {
+		return fmt.Errorf("after %v of %v occurrences: %w", seen, s.count, err)
+	}
+	return err
+}
+
+// ForHTTP is ready once a GET request to path on the host port bound to
+// containerPort answers with a 2xx status. A zero timeout means
+// DefaultWaitTimeout.
+func ForHTTP(containerPort int, path string, timeout time.Duration) WaitStrategy {
+	return &httpStrategy{port: containerPort, path: path, timeout: timeout}
+}
+
+type httpStrategy struct {
+	port    int
+	path    string
+	timeout time.Duration
+}
+
+func (s *httpStrategy) String() string {
+	return fmt.Sprintf("http %v on port %v", s.path, s.port)
+}
+
+func (s *httpStrategy) WaitUntilReady(ctx context.Context, r *ContainerRunner) error {
+	return r.poll(ctx, s.timeout, func(ctx context.Context) (bool, error) {
+		endpoint, err := r.Endpoint(ctx, s.port)
+		if err != nil {
+			return false, nil
+		}
+		url := fmt.Sprintf("http://%v/%v", endpoint, strings.TrimPrefix(s.path, "/"))
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return false, err
+		}
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return false, nil
+		}
+		resp.Body.Close()
+		return resp.StatusCode >= 200 && resp.StatusCode < 300, nil
+	})
+}
+
+// ForHealthy is ready once Docker reports the container as healthy, which
+// requires the container to have a healthcheck. A zero timeout means
+// DefaultWaitTimeout.
+func ForHealthy(timeout time.Duration) WaitStrategy {
+	return &healthyStrategy{timeout: timeout}
+}
+
+type healthyStrategy struct {
+	timeout time.Duration
+}
+
+func (s *healthyStrategy) String() string {
+	return "healthy"
+}
+
+func (s *healthyStrategy) WaitUntilReady(ctx context.Context, r *ContainerRunner) error {
+	return r.poll(ctx, s.timeout, func(ctx context.Context) (bool, error) {
+		info, err := r.client.ContainerInspect(ctx, r.id)
+		if err != nil {
+			return false, fmt.Errorf("inspecting container: %w", err)
+		}
+		if info.State == nil {
+			return false, nil
+		}
+		if info.State.Health == nil {
+			return false, ErrNoHealthcheck
+		}
+		return info.State.Health.Status == "healthy", nil
+	})
+}
+
+// ForFile is ready once path exists in the container, for images that signal
+// readiness by creating a file such as /tmp/ready. The file is looked up
+// every interval, or DefaultPollInterval if it is zero, without running
+// anything in the container. A zero timeout means DefaultWaitTimeout.
+func ForFile(path string, interval, timeout time.Duration) WaitStrategy {
+	return &fileStrategy{path: path, interval: interval, timeout: timeout}
+}
+
+type fileStrategy struct {
+	path     string
+	interval time.Duration
+	timeout  time.Duration
+}
+
+func (s *fileStrategy) String() string {
+	return fmt.Sprintf("file %v", s.path)
+}
+
+func (s *fileStrategy) WaitUntilReady(ctx context.Context, r *ContainerRunner) error {
+	return r.pollEvery(ctx, s.interval, s.timeout, func(ctx context.Context) (bool, error) {
+		archive, _, err := r.client.CopyFromContainer(ctx, r.id, s.path)
+		if err != nil {
+			err = classifyError(err)
+			if errors.Is(err, ErrPathNotFound) {
+				return false, nil
+			}
+			return false, fmt.Errorf("copying %v from container: %w", s.path, err)
+		}
+		archive.Close()
+		return true, nil
+	})
+}
+
+// ForAll is ready once all of the strategies passed, waited on in order. Each
+// strategy keeps its own timeout, e.g. a short one for a port and a long one
+// for a log line announcing that migrations ran, and timeout bounds all of
+// them together; a zero timeout only bounds them by ctx. The error names the
+// strategy that failed or was still waited on when timeout elapsed.
+func ForAll(timeout time.Duration, strategies ...WaitStrategy) WaitStrategy {
+	return &allStrategy{strategies: strategies, timeout: timeout}
+}
+
+type allStrategy struct {
+	strategies []WaitStrategy
+	timeout    time.Duration
+}
+
+func (s *allStrategy) String() string {
+	names := make([]string, len(s.strategies))
+	for i, strategy := range s.strategies {
+		names[i] = fmt.Sprint(strategy)
+	}
+	return fmt.Sprintf("all of [%v]", strings.Join(names, ", "))
+}
+
+func (s *allStrategy) WaitUntilReady(ctx context.Context, r *ContainerRunner) error {
+	parent := ctx
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+	for _, strategy := range s.strategies {
+		r.logger.Infof("waiting for %v", strategy)
+		if err := strategy.WaitUntilReady(ctx, r); err != nil {
+			if ctx.Err() != nil && parent.Err() == nil {
+				return fmt.Errorf("waiting for %v: overall timeout of %v elapsed: %w", strategy, s.timeout, err)
+			}
+			return fmt.Errorf("waiting for %v: %w", strategy, err)
+		}
+	}
+	return nil
+}
+
+// poll calls check every DefaultPollInterval until it reports true or fails,
+// for at most timeout (DefaultWaitTimeout if zero). It fails early with
+// ErrContainerExited if the container stops running.
+func (e *ContainerRunner) poll(ctx context.Context, timeout time.Duration, check func(ctx context.Context) (bool, error)) error {
+	return e.pollEvery(ctx, DefaultPollInterval, timeout, check)
+}
+
+// pollEvery is poll with the given interval, or DefaultPollInterval if it is
+// zero
+func (e *ContainerRunner) pollEvery(ctx context.Context, interval, timeout time.Duration, check func(ctx context.Context) (bool, error)) error {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ctx, cancel := context.WithTimeout(ctx, orDefaultTimeout(timeout))
+	defer cancel()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		atomic.AddInt32(&e.attempts, 1)
+		ok, err := check(ctx)
+		if err != nil {
+			return timeoutErr(ctx, err)
+		}
+		if ok {
+			return nil
+		}
+		info, err := e.client.ContainerInspect(ctx, e.id)
+		if err != nil {
+			return timeoutErr(ctx, fmt.Errorf("inspecting container: %w", err))
+		}
+		if info.State != nil && !info.State.Running && !info.State.Restarting {
+			return fmt.Errorf("%w with code %v", ErrContainerExited, info.State.ExitCode)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return timeoutErr(ctx, ctx.Err())
+		}
+	}
+}
+
+// dial returns true if the host port bound to containerPort accepts a TCP
+// connection
+func (e *ContainerRunner) dial(ctx context.Context, containerPort int) bool {
+	endpoint, err := e.Endpoint(ctx, containerPort)
+	if err != nil {
+		return false
+	}
+	return dialAddress(ctx, endpoint) == nil
+}
+
+// dialAddress opens and closes a TCP connection to address
+func dialAddress(ctx context.Context, address string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// WaitForPortClosed blocks until the host port bound to containerPort stops
+// accepting TCP connections, for at most timeout (DefaultWaitTimeout if
+// zero), e.g. to verify that a graceful shutdown released its sockets. It
+// fails with ErrWaitTimeout if the port is still open then. A port that is no
+// longer bound, because the container exited, is closed. Note that with
+// Docker's userland proxy the host port accepts connections for as long as
+// the container runs, even if nothing listens inside it.
+func (e *ContainerRunner) WaitForPortClosed(ctx context.Context, containerPort int, timeout time.Duration) error {
+	// If we don't have a container id
+	if len(e.id) == 0 {
+		return ErrNoContainerId
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, orDefaultTimeout(timeout))
+	defer cancel()
+	endpoint, err := e.Endpoint(ctx, containerPort)
+	if errors.Is(err, ErrPortNotBound) {
+		return nil
+	}
+	if err != nil {
+		return timeoutErr(ctx, err)
+	}
+
+	ticker := time.NewTicker(DefaultPollInterval)
+	defer ticker.Stop()
+	for {
+		err := dialAddress(ctx, endpoint)
+		var netErr net.Error
+		// A dial that timed out, such as when ctx expires, doesn't tell
+		// whether the port is closed
+		if err != nil && !(errors.As(err, &netErr) && netErr.Timeout()) && ctx.Err() == nil {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("port %v still open: %w", containerPort, timeoutErr(ctx, ctx.Err()))
+		}
+	}
+}
+
+// orDefaultTimeout returns timeout, or DefaultWaitTimeout if it is zero
+func orDefaultTimeout(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return DefaultWaitTimeout
+	}
+	return timeout
+}
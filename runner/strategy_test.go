@@ -0,0 +1,174 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestReadiness(t *testing.T) {
+	inspections := 0
+	runner := NewContainerRunner().
+		WithImage("postgres").
+		WithWaitForHealthy(0)
+	runner.client = &mockClient{
+		containerInspect: func(id string) (types.ContainerJSON, error) {
+			inspections++
+			info := runningContainer()
+			info.State.Health = &types.Health{Status: "starting"}
+			if inspections >= 5 {
+				info.State.Health.Status = "healthy"
+			}
+			return info, nil
+		},
+	}
+
+	require.NoError(t, runner.Start(context.Background()))
+	readiness := runner.Readiness()
+	require.Len(t, readiness.Strategies, 1)
+	require.Equal(t, "healthy", readiness.Strategies[0].Strategy)
+	require.Equal(t, 3, readiness.Strategies[0].Attempts)
+	require.True(t, readiness.Duration >= readiness.Strategies[0].Duration)
+}
+
+func TestWaitReadyConcurrently(t *testing.T) {
+	runner := NewContainerRunner().WithImage("postgres")
+	runner.id = "id"
+	runner.client = &mockClient{
+		containerInspect: func(id string) (types.ContainerJSON, error) {
+			info := runningContainer()
+			info.State.Health = &types.Health{Status: "healthy"}
+			return info, nil
+		},
+	}
+
+	const waiters = 8
+	errs := make(chan error, waiters)
+	for i := 0; i < waiters; i++ {
+		go func() {
+			_, err := runner.WaitReady(context.Background(), ForHealthy(0), ForAll(0, ForHealthy(0), ForHealthy(0)))
+			errs <- err
+		}()
+	}
+	for i := 0; i < waiters; i++ {
+		require.NoError(t, <-errs)
+	}
+	require.Equal(t, int32(3*waiters), runner.attempts)
+}
+
+func TestWithHealthCheck(t *testing.T) {
+	runner := NewContainerRunner().
+		WithImage("redis").
+		WithHealthCheck([]string{"CMD", "redis-cli", "ping"}, time.Second, 500*time.Millisecond, 3)
+	require.NoError(t, runner.err)
+	require.Equal(t, &container.HealthConfig{
+		Test:     []string{"CMD", "redis-cli", "ping"},
+		Interval: time.Second,
+		Timeout:  500 * time.Millisecond,
+		Retries:  3,
+	}, runner.containerConfig().Healthcheck)
+
+	runner = NewContainerRunner().WithHealthCheck([]string{"CMD-SHELL", "pg_isready", "-U", "postgres"}, 0, 0, 0)
+	require.Error(t, runner.err)
+}
+
+func TestWithWaitForLogOccurrences(t *testing.T) {
+	logs := func(lines ...string) func(id string, options types.ContainerLogsOptions) (io.ReadCloser, error) {
+		return func(id string, options types.ContainerLogsOptions) (io.ReadCloser, error) {
+			var b bytes.Buffer
+			w := stdcopy.NewStdWriter(&b, stdcopy.Stdout)
+			for _, line := range lines {
+				w.Write([]byte(line + "\n"))
+			}
+			return ioutil.NopCloser(&b), nil
+		}
+	}
+
+	runner := NewContainerRunner().
+		WithImage("gunicorn").
+		WithWaitForLogOccurrences("Booting worker", 2, time.Second)
+	runner.client = &mockClient{
+		containerLogs: logs("Listening at: http://0.0.0.0:8000", "Booting worker with pid: 8", "Booting worker with pid: 9"),
+	}
+	require.NoError(t, runner.Start(context.Background()))
+
+	runner = NewContainerRunner().
+		WithImage("gunicorn").
+		WithWaitForLogOccurrences("Booting worker", 3, time.Second)
+	runner.client = &mockClient{
+		containerLogs: logs("Booting worker with pid: 8", "Booting worker with pid: 9"),
+	}
+	err := runner.Start(context.Background())
+	require.True(t, errors.Is(err, ErrLogsEnded))
+	require.Contains(t, err.Error(), "after 2 of 3 occurrences")
+}
+
+func TestWithWaitForFile(t *testing.T) {
+	lookups := 0
+	runner := NewContainerRunner().
+		WithImage("app").
+		WithWaitForFile("/tmp/ready", time.Millisecond, time.Second)
+	runner.client = &mockClient{
+		containerInspect: func(id string) (types.ContainerJSON, error) {
+			return runningContainer(), nil
+		},
+		copyFrom: func(path string) (io.ReadCloser, types.ContainerPathStat, error) {
+			lookups++
+			if lookups < 3 {
+				return nil, types.ContainerPathStat{}, errors.New("Error response from daemon: Could not find the file /tmp/ready in container id")
+			}
+			return ioutil.NopCloser(&bytes.Buffer{}), types.ContainerPathStat{Name: "ready"}, nil
+		},
+	}
+
+	require.NoError(t, runner.Start(context.Background()))
+	require.Equal(t, 3, lookups)
+	require.Equal(t, "file /tmp/ready", runner.Readiness().Strategies[0].Strategy)
+
+	require.Error(t, NewContainerRunner().WithWaitForFile("/tmp/ready", -time.Second, 0).err)
+}
+
+func TestWithWaitForAll(t *testing.T) {
+	health := "healthy"
+	newRunner := func(timeout time.Duration, strategies ...WaitStrategy) *ContainerRunner {
+		runner := NewContainerRunner().
+			WithImage("app").
+			WithWaitForAll(timeout, strategies...)
+		runner.client = &mockClient{
+			containerInspect: func(id string) (types.ContainerJSON, error) {
+				info := runningContainer()
+				info.State.Health = &types.Health{Status: health}
+				return info, nil
+			},
+			copyFrom: func(path string) (io.ReadCloser, types.ContainerPathStat, error) {
+				return ioutil.NopCloser(&bytes.Buffer{}), types.ContainerPathStat{Name: "ready"}, nil
+			},
+		}
+		return runner
+	}
+
+	runner := newRunner(time.Second, ForFile("/tmp/ready", 0, 0), ForHealthy(0))
+	require.NoError(t, runner.Start(context.Background()))
+	require.Equal(t, "all of [file /tmp/ready, healthy]", runner.Readiness().Strategies[0].Strategy)
+
+	// The health check times out on its own while the file is present
+	health = "starting"
+	runner = newRunner(0, ForFile("/tmp/ready", 0, 0), ForHealthy(50*time.Millisecond))
+	err := runner.Start(context.Background())
+	require.True(t, errors.Is(err, ErrWaitTimeout))
+	require.Contains(t, err.Error(), "waiting for healthy: timed out")
+
+	// The overall timeout elapses before the one of the health check
+	runner = newRunner(50*time.Millisecond, ForFile("/tmp/ready", 0, 0), ForHealthy(time.Minute))
+	err = runner.Start(context.Background())
+	require.True(t, errors.Is(err, ErrWaitTimeout))
+	require.Contains(t, err.Error(), "waiting for healthy: overall timeout of 50ms elapsed")
+}
@@ -0,0 +1,53 @@
+package runner
+
+import (
+	"os"
+)
+
+const (
+	// KeepEnvironmentVariable is the environment variable that, when set to
+	// 1, keeps the containers of failed tests around
+	KeepEnvironmentVariable = "CONTAINER_KEEP"
+)
+
+// TestingT is the subset of testing.TB used by StartForTest
+type TestingT interface {
+	Helper()
+	Cleanup(func())
+	Failed() bool
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+	Logf(format string, args ...interface{})
+}
+
+// StartForTest starts the container and registers a cleanup with t that
+// stops it once the test and its subtests completed, both using the context
+// set with WithContext. The container is also stopped if Start fails after it
+// was created. If the test failed and either
+// ContainerRunnerOpts.KeepOnFailure is enabled or CONTAINER_KEEP=1 is set,
+// the container is not removed so that it can be inspected.
+func (e *ContainerRunner) StartForTest(t TestingT) {
+	t.Helper()
+	// Registered first, as Start can fail after the container was created
+	t.Cleanup(func() {
+		if len(e.id) == 0 {
+			return
+		}
+		var opts []StopOption
+		if e.opts.RemoveOnFinalization && t.Failed() && e.keepOnFailure() {
+			t.Logf("test failed, keeping container %v (%v)", e.name, e.id)
+			opts = append(opts, StopRemove(false))
+		}
+		if err := e.Stop(e.baseContext(), opts...); err != nil {
+			t.Errorf("stopping container: %v", err)
+		}
+	})
+	if err := e.Start(e.baseContext()); err != nil {
+		t.Fatalf("starting container: %v", err)
+	}
+}
+
+// keepOnFailure returns whether containers of failed tests should be kept
+func (e *ContainerRunner) keepOnFailure() bool {
+	return e.opts.KeepOnFailure || os.Getenv(KeepEnvironmentVariable) == "1"
+}
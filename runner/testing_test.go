@@ -0,0 +1,141 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
+	"testing"
+)
+
+// fakeT is a TestingT that records what the code under test reports
+type fakeT struct {
+	failed   bool
+	fatal    bool
+	cleanups []func()
+	logs     []string
+	errors   []string
+}
+
+func (t *fakeT) Helper()          {}
+func (t *fakeT) Cleanup(f func()) { t.cleanups = append(t.cleanups, f) }
+func (t *fakeT) Failed() bool     { return t.failed }
+func (t *fakeT) Logf(format string, args ...interface{}) {
+	t.logs = append(t.logs, fmt.Sprintf(format, args...))
+}
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.failed = true
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+func (t *fakeT) Fatalf(format string, args ...interface{}) {
+	t.Errorf(format, args...)
+	t.fatal = true
+}
+
+// finish runs the cleanups in reverse order like testing does
+func (t *fakeT) finish() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+func TestStartForTest(t *testing.T) {
+	defer os.Setenv(KeepEnvironmentVariable, os.Getenv(KeepEnvironmentVariable))
+	for _, tc := range []struct {
+		name    string
+		failed  bool
+		keep    bool
+		env     string
+		removed bool
+	}{
+		{name: "passed", keep: true, env: "1", removed: true},
+		{name: "failed", failed: true, removed: true},
+		{name: "failed with KeepOnFailure", failed: true, keep: true},
+		{name: "failed with CONTAINER_KEEP", failed: true, env: "1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, os.Setenv(KeepEnvironmentVariable, tc.env))
+			stopped, removed := false, false
+			runner := NewContainerRunner().WithImage("mongo").WithOptions(&ContainerRunnerOpts{
+				RemoveOnFinalization: true,
+				KeepOnFailure:        tc.keep,
+			})
+			runner.client = &mockClient{
+				containerStop: func(ctx context.Context, id string) error {
+					stopped = true
+					return nil
+				},
+				containerRemove: func(id string, options types.ContainerRemoveOptions) error {
+					removed = true
+					return nil
+				},
+			}
+
+			ft := &fakeT{}
+			runner.StartForTest(ft)
+			require.False(t, ft.failed)
+			require.Len(t, ft.cleanups, 1)
+			require.False(t, stopped)
+
+			ft.failed = tc.failed
+			ft.finish()
+			require.True(t, stopped)
+			require.Equal(t, tc.removed, removed)
+			require.Empty(t, ft.errors)
+			if !tc.removed {
+				require.Len(t, ft.logs, 1)
+				require.Contains(t, ft.logs[0], "keeping container")
+			}
+		})
+	}
+}
+
+func TestStartForTestFailure(t *testing.T) {
+	removed := false
+	runner := NewContainerRunner().WithImage("mongo").WithOptions(&ContainerRunnerOpts{
+		RemoveOnFinalization: true,
+	})
+	runner.client = &mockClient{
+		// Fails after the container was created
+		containerStart: func(id string) error {
+			return errors.New("port is already allocated")
+		},
+		containerRemove: func(id string, options types.ContainerRemoveOptions) error {
+			require.Equal(t, "id", id)
+			removed = true
+			return nil
+		},
+	}
+	ft := &fakeT{}
+	runner.StartForTest(ft)
+	require.True(t, ft.fatal)
+	require.Contains(t, ft.errors[0], "port is already allocated")
+
+	ft.finish()
+	require.True(t, removed)
+	require.Len(t, ft.errors, 1)
+}
+
+func TestStartForTestNotCreated(t *testing.T) {
+	stopped := false
+	runner := NewContainerRunner().WithImage("mongo")
+	runner.client = &mockClient{
+		imagePull: func(ref string) (io.ReadCloser, error) {
+			return nil, errors.New("repository does not exist")
+		},
+		containerStop: func(ctx context.Context, id string) error {
+			stopped = true
+			return nil
+		},
+	}
+	ft := &fakeT{}
+	runner.StartForTest(ft)
+	require.True(t, ft.fatal)
+
+	ft.finish()
+	require.False(t, stopped)
+	require.Len(t, ft.errors, 1)
+}
@@ -0,0 +1,41 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+const (
+	// DefaultZoneinfoPath is where the host's IANA time zone database is
+	// read from by WithTimezone
+	DefaultZoneinfoPath = "/usr/share/zoneinfo"
+)
+
+// timezonePattern matches IANA time zone names such as "UTC", "Europe/Berlin"
+// or "America/Argentina/Buenos_Aires"
+var timezonePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_+\-]*(/[A-Za-z0-9_+\-]+)*$`)
+
+// WithTimezone sets the time zone of the container through the TZ environment
+// variable, e.g. "Europe/Berlin". TZ is only honored by images that ship the
+// time zone database (tzdata); for images that don't, set mountLocaltime to
+// bind mount the zone's file from the host's time zone database read-only at
+// /etc/localtime.
+func (r *ContainerRunner) WithTimezone(tz string, mountLocaltime bool) *ContainerRunner {
+	if !timezonePattern.MatchString(tz) {
+		r.setErr(fmt.Errorf("invalid time zone %q", tz))
+		return r
+	}
+	r.WithEnvironmentVariable("TZ", tz)
+	if !mountLocaltime {
+		return r
+	}
+
+	zoneinfo := filepath.Join(DefaultZoneinfoPath, tz)
+	if _, err := os.Stat(zoneinfo); err != nil {
+		r.setErr(fmt.Errorf("time zone %v: %w", tz, err))
+		return r
+	}
+	return r.WithVolume(zoneinfo, "/etc/localtime", MountReadOnly())
+}
@@ -0,0 +1,36 @@
+package runner
+
+import (
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithTimezone(t *testing.T) {
+	for _, tz := range []string{"", "../etc", "Europe/../../etc/passwd", "/Europe/Berlin", "Europe Berlin"} {
+		runner := NewContainerRunner().WithImage("alpine").WithTimezone(tz, false)
+		require.Error(t, runner.err, tz)
+		require.Empty(t, runner.env, tz)
+	}
+
+	runner := NewContainerRunner().WithImage("alpine").WithTimezone("America/Argentina/Buenos_Aires", false)
+	require.NoError(t, runner.err)
+	require.Equal(t, []string{"TZ=America/Argentina/Buenos_Aires"}, runner.env)
+	require.Empty(t, runner.mounts)
+
+	runner = NewContainerRunner().WithImage("alpine").WithTimezone("Mars/Olympus_Mons", true)
+	require.Error(t, runner.err)
+
+	zoneinfo := filepath.Join(DefaultZoneinfoPath, "Europe/Berlin")
+	if _, err := os.Stat(zoneinfo); err != nil {
+		t.Skipf("no time zone database on the host: %v", err)
+	}
+	runner = NewContainerRunner().WithImage("alpine").WithTimezone("Europe/Berlin", true)
+	require.NoError(t, runner.err)
+	require.Equal(t, []string{"TZ=Europe/Berlin"}, runner.env)
+	require.Len(t, runner.mounts, 1)
+	require.Equal(t, zoneinfo, runner.mounts[0].Source)
+	require.Equal(t, "/etc/localtime", runner.mounts[0].Target)
+	require.True(t, runner.mounts[0].ReadOnly)
+}
@@ -0,0 +1,59 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"github.com/docker/docker/api/types/container"
+)
+
+const (
+	// cpuPeriod is the CFS scheduler period used by SetCPULimit, in
+	// microseconds
+	cpuPeriod = 100000
+	// minCPUQuota is the smallest CPU quota Docker accepts, in microseconds
+	minCPUQuota = 1000
+)
+
+// UpdateResources updates the resource limits of the running container
+func (e *ContainerRunner) UpdateResources(ctx context.Context, config container.UpdateConfig) error {
+	// If we don't have a container id
+	if len(e.id) == 0 {
+		return ErrNoContainerId
+	}
+
+	resp, err := e.client.ContainerUpdate(ctx, e.id, config)
+	if err != nil {
+		return fmt.Errorf("updating container: %w", err)
+	}
+	for _, warning := range resp.Warnings {
+		e.logger.Warnln(warning)
+	}
+	return nil
+}
+
+// SetMemoryLimit updates the memory limit of the running container, in bytes
+func (e *ContainerRunner) SetMemoryLimit(ctx context.Context, bytes int64) error {
+	// A zero limit would leave the limit unchanged instead
+	if bytes <= 0 {
+		return fmt.Errorf("memory limit %v must be positive", bytes)
+	}
+	return e.UpdateResources(ctx, container.UpdateConfig{
+		Resources: container.Resources{Memory: bytes},
+	})
+}
+
+// SetCPULimit updates how many CPUs the running container may use, e.g. 1.5.
+// The smallest limit is 0.01 CPUs.
+func (e *ContainerRunner) SetCPULimit(ctx context.Context, cpus float64) error {
+	quota := int64(cpus * cpuPeriod)
+	// A zero quota would remove the limit instead
+	if quota < minCPUQuota {
+		return fmt.Errorf("cpu limit %v must be at least %v", cpus, float64(minCPUQuota)/cpuPeriod)
+	}
+	return e.UpdateResources(ctx, container.UpdateConfig{
+		Resources: container.Resources{
+			CPUPeriod: cpuPeriod,
+			CPUQuota:  quota,
+		},
+	})
+}
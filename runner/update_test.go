@@ -0,0 +1,46 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestUpdateResources(t *testing.T) {
+	var updates []container.Resources
+	runner := NewContainerRunner()
+	require.Equal(t, ErrNoContainerId, runner.SetMemoryLimit(context.Background(), 1<<30))
+
+	runner.id = "id"
+	runner.client = &mockClient{
+		containerUpdate: func(id string, config container.UpdateConfig) (container.ContainerUpdateOKBody, error) {
+			updates = append(updates, config.Resources)
+			if config.Memory == 1 {
+				return container.ContainerUpdateOKBody{}, errors.New("minimum memory limit allowed is 6MB")
+			}
+			return container.ContainerUpdateOKBody{Warnings: []string{"swap limit not supported"}}, nil
+		},
+	}
+	require.NoError(t, runner.UpdateResources(context.Background(), container.UpdateConfig{
+		Resources: container.Resources{PidsLimit: 100},
+	}))
+	require.NoError(t, runner.SetMemoryLimit(context.Background(), 1<<30))
+	require.NoError(t, runner.SetCPULimit(context.Background(), 1.5))
+	require.Error(t, runner.SetMemoryLimit(context.Background(), 1))
+	require.Equal(t, []container.Resources{
+		{PidsLimit: 100},
+		{Memory: 1 << 30},
+		{CPUPeriod: 100000, CPUQuota: 150000},
+		{Memory: 1},
+	}, updates)
+
+	for _, cpus := range []float64{0, -1, 0.001} {
+		require.Error(t, runner.SetCPULimit(context.Background(), cpus))
+	}
+	for _, bytes := range []int64{0, -1} {
+		require.Error(t, runner.SetMemoryLimit(context.Background(), bytes))
+	}
+	require.Len(t, updates, 4)
+}
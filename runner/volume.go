@@ -0,0 +1,111 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+	"strings"
+)
+
+// DefaultVolumeDriver is the driver of volumes created by CreateVolume when
+// none is given
+const DefaultVolumeDriver = "local"
+
+// VolumeOptions configures a volume created with CreateVolume. Empty fields
+// keep Docker's defaults.
+type VolumeOptions struct {
+	// Driver is the volume driver, DefaultVolumeDriver if empty
+	Driver string
+	// DriverOpts are passed to the driver, e.g. for the local driver
+	// {"type": "nfs", "o": "addr=10.0.0.1,rw", "device": ":/exports/data"}
+	DriverOpts map[string]string
+	// Labels are added to the volume
+	Labels map[string]string
+}
+
+// WithNamedVolume mounts the named volume into the container at
+// containerPath. Docker creates the volume with its defaults if it doesn't
+// exist; use CreateVolume first to configure its driver.
+func (r *ContainerRunner) WithNamedVolume(name, containerPath string, opts ...MountOption) *ContainerRunner {
+	if len(name) == 0 {
+		r.setErr(errors.New("volume name must not be empty"))
+		return r
+	}
+	return r.withMount(mount.Mount{
+		Type:   mount.TypeVolume,
+		Source: name,
+		Target: containerPath,
+	}, opts)
+}
+
+// CreateVolume creates a named volume and returns its name. It fails with
+// ErrUnknownVolumeDriver if the daemon doesn't have the driver. Creating a
+// volume that already exists with the same driver returns it unchanged.
+func CreateVolume(ctx context.Context, name string, opts VolumeOptions) (string, error) {
+	c, err := client.NewEnvClient()
+	if err != nil {
+		return "", fmt.Errorf("creating env client: %w", err)
+	}
+	defer c.Close()
+	return createVolume(ctx, c, name, opts)
+}
+
+// createVolume creates the volume using c
+func createVolume(ctx context.Context, c client.CommonAPIClient, name string, opts VolumeOptions) (string, error) {
+	driver := opts.Driver
+	if len(driver) == 0 {
+		driver = DefaultVolumeDriver
+	}
+	info, err := c.Info(ctx)
+	if err != nil {
+		return "", fmt.Errorf("getting daemon info: %w", err)
+	}
+	if !hasVolumeDriver(info.Plugins.Volume, driver) {
+		return "", fmt.Errorf("creating volume %v: driver %v: %w", name, driver, ErrUnknownVolumeDriver)
+	}
+
+	vol, err := c.VolumeCreate(ctx, volume.VolumesCreateBody{
+		Name:       name,
+		Driver:     driver,
+		DriverOpts: opts.DriverOpts,
+		Labels:     opts.Labels,
+	})
+	if err != nil {
+		return "", fmt.Errorf("creating volume %v: %w", name, err)
+	}
+	return vol.Name, nil
+}
+
+// hasVolumeDriver returns true if driver is one of the volume plugins of the
+// daemon, which lists plugins installed with `docker plugin install` with
+// their tag
+func hasVolumeDriver(plugins []string, driver string) bool {
+	for _, plugin := range plugins {
+		if plugin == driver || strings.TrimSuffix(plugin, ":latest") == driver {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveVolume removes the named volume, which must not be used by any
+// container
+func RemoveVolume(ctx context.Context, name string) error {
+	c, err := client.NewEnvClient()
+	if err != nil {
+		return fmt.Errorf("creating env client: %w", err)
+	}
+	defer c.Close()
+	return removeVolume(ctx, c, name)
+}
+
+// removeVolume removes the volume using c
+func removeVolume(ctx context.Context, c client.CommonAPIClient, name string) error {
+	if err := c.VolumeRemove(ctx, name, false); err != nil {
+		return fmt.Errorf("removing volume %v: %w", name, err)
+	}
+	return nil
+}
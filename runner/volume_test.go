@@ -0,0 +1,71 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCreateVolume(t *testing.T) {
+	var created volume.VolumesCreateBody
+	c := &mockClient{
+		info: func() (types.Info, error) {
+			return types.Info{Plugins: types.PluginsInfo{Volume: []string{"local", "vieux/sshfs:latest"}}}, nil
+		},
+		volumeCreate: func(options volume.VolumesCreateBody) (types.Volume, error) {
+			created = options
+			return types.Volume{Name: options.Name, Driver: options.Driver}, nil
+		},
+	}
+
+	opts := VolumeOptions{DriverOpts: map[string]string{"type": "nfs", "device": ":/exports/data"}}
+	name, err := createVolume(context.Background(), c, "data", opts)
+	require.NoError(t, err)
+	require.Equal(t, "data", name)
+	require.Equal(t, volume.VolumesCreateBody{Name: "data", Driver: "local", DriverOpts: opts.DriverOpts}, created)
+
+	_, err = createVolume(context.Background(), c, "remote", VolumeOptions{Driver: "vieux/sshfs"})
+	require.NoError(t, err)
+
+	_, err = createVolume(context.Background(), c, "remote", VolumeOptions{Driver: "rexray/ebs"})
+	require.True(t, errors.Is(err, ErrUnknownVolumeDriver))
+}
+
+func TestRemoveVolume(t *testing.T) {
+	var removed []string
+	c := &mockClient{
+		volumeRemove: func(name string, force bool) error {
+			require.False(t, force)
+			if name == "missing" {
+				return notFoundError{}
+			}
+			removed = append(removed, name)
+			return nil
+		},
+	}
+
+	require.NoError(t, removeVolume(context.Background(), c, "data"))
+	require.Equal(t, []string{"data"}, removed)
+
+	err := removeVolume(context.Background(), c, "missing")
+	require.Error(t, err)
+	require.True(t, errors.Is(err, notFoundError{}))
+}
+
+func TestWithNamedVolume(t *testing.T) {
+	runner := NewContainerRunner().WithNamedVolume("data", "/var/lib/postgresql/data", MountReadOnly())
+	require.NoError(t, runner.err)
+	require.Equal(t, []mount.Mount{{
+		Type:     mount.TypeVolume,
+		Source:   "data",
+		Target:   "/var/lib/postgresql/data",
+		ReadOnly: true,
+	}}, runner.mounts)
+
+	require.Error(t, NewContainerRunner().WithNamedVolume("", "/data").err)
+	require.Error(t, NewContainerRunner().WithNamedVolume("data", "/data", MountConsistency(ConsistencyCached)).err)
+}
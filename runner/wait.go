@@ -0,0 +1,120 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/client"
+	"io"
+	"net"
+	"time"
+)
+
+const (
+	// DefaultWaitAttempts is the maximum number of times WaitForExit issues
+	// a wait request before giving up on recoverable errors
+	DefaultWaitAttempts = 5
+	// DefaultWaitBackoff is the delay before the first re-issued wait
+	// request. It doubles with every attempt.
+	DefaultWaitBackoff = 500 * time.Millisecond
+	// DefaultRemovalTimeout is how long WithForceRecreate waits for an
+	// existing container to be removed
+	DefaultRemovalTimeout = 30 * time.Second
+	// DefaultRemovalPollInterval is how often the container is inspected
+	// while waiting for its removal
+	DefaultRemovalPollInterval = 100 * time.Millisecond
+)
+
+// waitBackoff is the delay before the first re-issued wait request, which
+// tests shorten
+var waitBackoff = DefaultWaitBackoff
+
+// WaitForExit blocks until the container exits and returns its exit code.
+//
+// The wait request is a long-lived connection to the daemon that can drop if
+// the daemon restarts or the connection times out. When that happens the
+// request is re-issued with an exponential backoff, starting at
+// DefaultWaitBackoff, for at most DefaultWaitAttempts attempts in total.
+// Waiting on a container that already exited returns immediately, so an
+// exit that happens while disconnected is still observed. Errors that are
+// not connection related, such as the container not existing, and the
+// cancellation of ctx are returned right away.
+func (e *ContainerRunner) WaitForExit(ctx context.Context) (int64, error) {
+	// If we don't have a container id
+	if len(e.id) == 0 {
+		return -1, ErrNoContainerId
+	}
+
+	backoff := waitBackoff
+	var err error
+	for attempt := 1; attempt <= DefaultWaitAttempts; attempt++ {
+		var code int64
+		code, err = e.client.ContainerWait(ctx, e.id)
+		if err == nil {
+			return code, nil
+		}
+		if ctx.Err() != nil {
+			return -1, ctx.Err()
+		}
+		if !e.isRetryable(err, isRecoverableStreamError) || attempt == DefaultWaitAttempts {
+			break
+		}
+
+		e.logger.Warnf("waiting for container failed (attempt %v/%v), retrying in %v: %v", attempt, DefaultWaitAttempts, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return -1, ctx.Err()
+		}
+		backoff *= 2
+	}
+	return -1, fmt.Errorf("waiting for container: %w", err)
+}
+
+// WaitForRemoval blocks until the container no longer exists, for at most
+// timeout. Removal is asynchronous on some daemons, so creating a container
+// with the same name right after Stop can race with the removal.
+func (e *ContainerRunner) WaitForRemoval(ctx context.Context, timeout time.Duration) error {
+	// If we don't have a container id
+	if len(e.id) == 0 {
+		return ErrNoContainerId
+	}
+	return e.waitForRemoval(ctx, e.id, timeout)
+}
+
+// waitForRemoval polls the container with the given id or name until the
+// daemon reports that it does not exist
+func (e *ContainerRunner) waitForRemoval(ctx context.Context, ref string, timeout time.Duration) error {
+	deadline := time.After(timeout)
+	ticker := time.NewTicker(DefaultRemovalPollInterval)
+	defer ticker.Stop()
+	for {
+		_, err := e.client.ContainerInspect(ctx, ref)
+		if client.IsErrNotFound(err) {
+			return nil
+		}
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("inspecting container: %w", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			return fmt.Errorf("container %v: %w", ref, ErrRemovalTimeout)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// isRecoverableStreamError returns true if err indicates that the connection
+// to the daemon was lost rather than that the request itself failed
+func isRecoverableStreamError(err error) bool {
+	if client.IsErrConnectionFailed(err) {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
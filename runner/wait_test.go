@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestWaitForRemoval(t *testing.T) {
+	// The daemon keeps reporting the container for a few polls after the
+	// removal request returned
+	inspections := 0
+	runner := NewContainerRunner()
+	runner.id = "id"
+	runner.client = &mockClient{
+		containerInspect: func(id string) (types.ContainerJSON, error) {
+			inspections++
+			if inspections < 3 {
+				return runningContainer(), nil
+			}
+			return types.ContainerJSON{}, notFoundError{}
+		},
+	}
+
+	err := runner.WaitForRemoval(context.Background(), time.Second)
+	require.NoError(t, err)
+	require.Equal(t, 3, inspections)
+
+	inspections = -100
+	err = runner.WaitForRemoval(context.Background(), 150*time.Millisecond)
+	require.True(t, errors.Is(err, ErrRemovalTimeout))
+}
+
+func TestWithForceRecreate(t *testing.T) {
+	var events []string
+	removed := false
+	runner := NewContainerRunner().
+		WithName("mongo").
+		WithImage("mongo").
+		WithForceRecreate()
+	runner.client = &mockClient{
+		containerRemove: func(id string, options types.ContainerRemoveOptions) error {
+			require.True(t, options.Force)
+			events = append(events, "remove "+id)
+			removed = true
+			return nil
+		},
+		containerInspect: func(id string) (types.ContainerJSON, error) {
+			if removed {
+				removed = false
+				return runningContainer(), nil
+			}
+			events = append(events, "removed "+id)
+			return types.ContainerJSON{}, notFoundError{}
+		},
+		containerCreate: func(name string) (container.ContainerCreateCreatedBody, error) {
+			events = append(events, "create "+name)
+			return container.ContainerCreateCreatedBody{ID: "id"}, nil
+		},
+	}
+
+	require.NoError(t, runner.Start(context.Background()))
+	require.Equal(t, []string{"remove mongo", "removed mongo", "create mongo"}, events)
+}
+
+func TestWaitForExit(t *testing.T) {
+	defer func(backoff time.Duration) { waitBackoff = backoff }(waitBackoff)
+	waitBackoff = time.Millisecond
+
+	dropped := fmt.Errorf("reading response: %w", io.ErrUnexpectedEOF)
+	attempts := 0
+	runner := NewContainerRunner()
+	runner.id = "id"
+	runner.client = &mockClient{
+		containerWait: func(id string) (int64, error) {
+			attempts++
+			if attempts < 3 {
+				return 0, dropped
+			}
+			return 7, nil
+		},
+	}
+	code, err := runner.WaitForExit(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(7), code)
+	require.Equal(t, 3, attempts)
+
+	attempts = 0
+	runner.client = &mockClient{
+		containerWait: func(id string) (int64, error) {
+			attempts++
+			return 0, fmt.Errorf("attempt %v: %w", attempts, dropped)
+		},
+	}
+	_, err = runner.WaitForExit(context.Background())
+	require.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+	require.Contains(t, err.Error(), fmt.Sprintf("attempt %v", DefaultWaitAttempts))
+	require.Equal(t, DefaultWaitAttempts, attempts)
+
+	attempts = 0
+	runner.client = &mockClient{
+		containerWait: func(id string) (int64, error) {
+			attempts++
+			return 0, notFoundError{}
+		},
+	}
+	_, err = runner.WaitForExit(context.Background())
+	require.Error(t, err)
+	require.Equal(t, 1, attempts)
+}